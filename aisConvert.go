@@ -1,94 +1,79 @@
 package main
 
 import (
+	"archive/zip"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/ngyewch/nmea-logger/format"
 	"github.com/ngyewch/nmea-logger/ioutil"
 	"github.com/urfave/cli/v3"
 )
 
-type RecordPreprocessor interface {
-	PreprocessRecord(record *format.AISRecord) error
-}
-
 func doAisConvert(ctx context.Context, cmd *cli.Command) error {
 	inputFile := cmd.StringArg(inputFileArg.Name)
 	outputFile := cmd.StringArg(outputFileArg.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
 	if inputFile == "" {
 		return fmt.Errorf(inputFileArg.Name + " is required")
 	}
+	csvOptions, err := newCsvOptions(cmd)
+	if err != nil {
+		return err
+	}
 
 	var recordWriter format.AISRecordWriter
+	// The writers are closed explicitly on success, as some of them (e.g. GeoJSON and KML) write their output on Close.
+	var closers ioutil.Closers
+	defer func() {
+		_ = closers.Close()
+	}()
 
-	if outputFile != "" {
-		f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+	switch {
+	case (outputFile == "") || ioutil.IsStdioPath(outputFile):
+		recordWriter, err = newAISRecordWriter(cmd, cmd.String(convertFormatFlag.Name), os.Stdout, csvOptions, &closers)
 		if err != nil {
 			return err
 		}
-		defer func(f io.WriteCloser) {
-			_ = f.Close()
-		}(f)
-
-		ext := filepath.Ext(outputFile1)
-		switch ext {
-		case ".jsonl":
-			recordWriter = format.NewJsonlAISRecordWriter(f)
-			defer func(recordWriter format.AISRecordWriter) {
-				_ = recordWriter.Close()
-			}(recordWriter)
-
-		case ".csv":
-			recordWriter, err = format.NewCsvAISRecordWriter(f)
-			if err != nil {
-				return err
-			}
-			defer func(recordWriter format.AISRecordWriter) {
-				_ = recordWriter.Close()
-			}(recordWriter)
-
-		default:
-			return fmt.Errorf("unsupported file extension")
+
+	case isDirectoryPath(outputFile):
+		dirWriter, err := ioutil.NewDirWriter(outputFile)
+		if err != nil {
+			return err
 		}
-	} else {
-		recordWriter = format.NewJsonlAISRecordWriter(os.Stdout)
-		defer func(recordWriter format.AISRecordWriter) {
-			_ = recordWriter.Close()
-		}(recordWriter)
-	}
+		closers = append(closers, dirWriter)
 
-	const ignoreParseErrors = true
+		recordWriter, err = format.NewMultiTableCsvAISRecordWriter(dirWriter, csvOptions)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, recordWriter)
 
-	recordPreprocessor, ok := recordWriter.(RecordPreprocessor)
-	if ok {
-		reader, err := ioutil.OpenFileForReading(inputFile)
+	default:
+		f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
 		if err != nil {
 			return err
 		}
-		defer func(reader io.ReadCloser) {
-			_ = reader.Close()
-		}(reader)
-		loggerRecordReader := format.NewLoggerRecordReader(reader)
-		aisRecordReader := format.NewAISRecordReader(loggerRecordReader, ignoreParseErrors)
-		for {
-			aisRecord, err := aisRecordReader.ReadAISRecord()
-			if err != nil {
-				return err
-			}
-			if aisRecord == nil {
-				break
-			}
-			err = recordPreprocessor.PreprocessRecord(aisRecord)
-			if err != nil {
-				return err
-			}
+		closers = append(closers, f)
+
+		outputFormat := strings.TrimPrefix(filepath.Ext(outputFile1), ".")
+		if !slices.Contains(convertFormats, outputFormat) {
+			return fmt.Errorf("unsupported file extension")
+		}
+		recordWriter, err = newAISRecordWriter(cmd, outputFormat, f, csvOptions, &closers)
+		if err != nil {
+			return err
 		}
 	}
 
+	const ignoreParseErrors = true
+
 	reader, err := ioutil.OpenFileForReading(inputFile)
 	if err != nil {
 		return err
@@ -98,6 +83,10 @@ func doAisConvert(ctx context.Context, cmd *cli.Command) error {
 	}(reader)
 	loggerRecordReader := format.NewLoggerRecordReader(reader)
 	aisRecordReader := format.NewAISRecordReader(loggerRecordReader, ignoreParseErrors)
+	aisRecordReader.SetDedupeWindow(dedupeWindow)
+	// JSONL output keeps undecodable messages, with their payload.
+	_, ok := recordWriter.(*format.JsonlAISRecordWriter)
+	aisRecordReader.SetKeepUndecodable(ok)
 	for {
 		aisRecord, err := aisRecordReader.ReadAISRecord()
 		if err != nil {
@@ -112,5 +101,93 @@ func doAisConvert(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
-	return nil
+	err = closeInOrder(closers)
+	closers = nil
+	return err
+}
+
+// convertFormats are the output formats of ais convert, named after their file extensions.
+var convertFormats = []string{"jsonl", "csv", "zip", "geojson", "kml"}
+
+// newAISRecordWriter returns a writer of the given output format to w. The writers to be closed are added to closers.
+func newAISRecordWriter(cmd *cli.Command, outputFormat string, w io.Writer, csvOptions format.CsvOptions, closers *ioutil.Closers) (format.AISRecordWriter, error) {
+	var recordWriter format.AISRecordWriter
+	switch outputFormat {
+	case "jsonl":
+		jsonlRecordWriter := format.NewJsonlAISRecordWriter(w)
+		jsonlRecordWriter.SetEnrichment(cmd.Bool(enrichFlag.Name))
+		jsonlRecordWriter.SetRaw(cmd.Bool(rawFlag.Name))
+		recordWriter = jsonlRecordWriter
+
+	case "csv":
+		var err error
+		recordWriter, err = format.NewCsvAISRecordWriter(w, csvOptions)
+		if err != nil {
+			return nil, err
+		}
+
+	case "zip":
+		zipWriter := zip.NewWriter(w)
+		*closers = append(*closers, zipWriter)
+		var err error
+		recordWriter, err = format.NewMultiTableCsvAISRecordWriter(zipWriter, csvOptions)
+		if err != nil {
+			return nil, err
+		}
+
+	case "geojson":
+		recordWriter = format.NewGeoJsonAISRecordWriter(w)
+
+	case "kml":
+		recordWriter = format.NewKmlAISRecordWriter(w)
+
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", outputFormat)
+	}
+	*closers = append(*closers, recordWriter)
+	return recordWriter, nil
+}
+
+func newCsvOptions(cmd *cli.Command) (format.CsvOptions, error) {
+	options := format.DefaultCsvOptions()
+	location, err := time.LoadLocation(cmd.String(timezoneFlag.Name))
+	if err != nil {
+		return options, err
+	}
+	delimiter, err := format.ParseCsvDelimiter(cmd.String(delimiterFlag.Name))
+	if err != nil {
+		return options, err
+	}
+	options.Columns = cmd.StringSlice(csvColumnsFlag.Name)
+	options.Location = location
+	options.DateTimeLayout = cmd.String(timeLayoutFlag.Name)
+	options.EtaLayout = cmd.String(etaLayoutFlag.Name)
+	options.CoordinatePrecision = cmd.Int(precisionFlag.Name)
+	options.Delimiter = delimiter
+	options.StaticDataLookahead = cmd.Duration(staticLookaheadFlag.Name)
+	options.Enrich = cmd.Bool(enrichFlag.Name)
+	options.Raw = cmd.Bool(rawFlag.Name)
+	return options, nil
+}
+
+// closeInOrder closes closers in reverse order, and returns the first error.
+func closeInOrder(closers []io.Closer) error {
+	var firstErr error
+	for i := len(closers) - 1; i >= 0; i-- {
+		err := closers[i].Close()
+		if (err != nil) && (firstErr == nil) {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// isDirectoryPath reports whether path names a directory, either because it ends with a path separator or because it
+// is an existing directory.
+func isDirectoryPath(path string) bool {
+	if os.IsPathSeparator(path[len(path)-1]) {
+		return true
+	}
+	fileInfo, err := os.Stat(path)
+	return (err == nil) && fileInfo.IsDir()
 }
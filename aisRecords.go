@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+)
+
+// aisRecordOptions selects the AIS records read by readAISRecords. Records from all MMSIs are read if MMSIs is empty.
+// Messages whose payload cannot be decoded are skipped unless KeepUndecodable is set; their records have a nil Packet.
+type aisRecordOptions struct {
+	MMSIs           []uint32
+	DedupeWindow    time.Duration
+	KeepUndecodable bool
+}
+
+// readAISRecords reads the decoded AIS records of the input files, and calls fn for each selected record. Malformed
+// records, e.g. a line truncated when the logger was stopped, are skipped with a warning.
+func readAISRecords(inputFiles []string, options aisRecordOptions, fn func(aisRecord *format.AISRecord) error) error {
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	return readAISRecordsFrom(reader, options, fn)
+}
+
+// readAISRecordsFrom is readAISRecords for a log that is already open.
+func readAISRecordsFrom(reader io.Reader, options aisRecordOptions, fn func(aisRecord *format.AISRecord) error) error {
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	aisRecordReader := format.NewAISRecordReader(loggerRecordReader, true)
+	aisRecordReader.SetDedupeWindow(options.DedupeWindow)
+	aisRecordReader.SetKeepUndecodable(options.KeepUndecodable)
+	for {
+		aisRecord, err := aisRecordReader.ReadAISRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				log.Warn("skipping malformed record",
+					slog.Int("line", malformedRecordError.Line),
+				)
+				continue
+			}
+			return err
+		}
+		if aisRecord == nil {
+			return nil
+		}
+		if (len(options.MMSIs) > 0) && !slices.Contains(options.MMSIs, aisRecord.Header().UserID) {
+			continue
+		}
+		err = fn(aisRecord)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ngyewch/nmea-logger/format"
+)
+
+func TestReadAISRecordsSkipsMalformedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	err := os.WriteFile(path, []byte(`{"timestamp":1000,"nmea":"!AIVDM,1,1,,A,B5BBokh0Sinm@R0:<>jbn8TT0000,0*70"}
+not json
+{"timestamp":2000,"nmea":"!AIVDM,1,1,,B,H5BBoki<Tn1HE=<Dj3400000000,2*25"}
+{"timestamp":17924300`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timestamps []int64
+	err = readAISRecords([]string{path}, aisRecordOptions{}, func(aisRecord *format.AISRecord) error {
+		timestamps = append(timestamps, aisRecord.Timestamp)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readAISRecords() error = %v", err)
+	}
+	if (len(timestamps) != 2) || (timestamps[0] != 1000) || (timestamps[1] != 2000) {
+		t.Errorf("timestamps = %v, want [1000 2000]", timestamps)
+	}
+}
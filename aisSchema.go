@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/urfave/cli/v3"
+)
+
+func doAisSchema(ctx context.Context, cmd *cli.Command) error {
+	jsonEncoder := json.NewEncoder(os.Stdout)
+	jsonEncoder.SetIndent("", "  ")
+	return jsonEncoder.Encode(format.AISMessageJSONSchema())
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -24,6 +25,7 @@ import (
 
 type PlaybackRecord interface {
 	GetTimestamp() int64
+	GetPacket() ais.Packet
 }
 
 type PositionReportRecord struct {
@@ -36,6 +38,10 @@ func (record *PositionReportRecord) GetTimestamp() int64 {
 	return record.T
 }
 
+func (record *PositionReportRecord) GetPacket() ais.Packet {
+	return record.PositionReport
+}
+
 type ShipStaticDataRecord struct {
 	Type           string             `json:"type"`
 	T              int64              `json:"t"`
@@ -46,6 +52,108 @@ func (record *ShipStaticDataRecord) GetTimestamp() int64 {
 	return record.T
 }
 
+func (record *ShipStaticDataRecord) GetPacket() ais.Packet {
+	return record.ShipStaticData
+}
+
+type StandardClassBPositionReportRecord struct {
+	Type                         string                           `json:"type"`
+	T                            int64                            `json:"t"`
+	StandardClassBPositionReport ais.StandardClassBPositionReport `json:"standardClassBPositionReport"`
+}
+
+func (record *StandardClassBPositionReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *StandardClassBPositionReportRecord) GetPacket() ais.Packet {
+	return record.StandardClassBPositionReport
+}
+
+type ExtendedClassBPositionReportRecord struct {
+	Type                         string                           `json:"type"`
+	T                            int64                            `json:"t"`
+	ExtendedClassBPositionReport ais.ExtendedClassBPositionReport `json:"extendedClassBPositionReport"`
+}
+
+func (record *ExtendedClassBPositionReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *ExtendedClassBPositionReportRecord) GetPacket() ais.Packet {
+	return record.ExtendedClassBPositionReport
+}
+
+type StaticDataReportRecord struct {
+	Type             string               `json:"type"`
+	T                int64                `json:"t"`
+	StaticDataReport ais.StaticDataReport `json:"staticDataReport"`
+}
+
+func (record *StaticDataReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *StaticDataReportRecord) GetPacket() ais.Packet {
+	return record.StaticDataReport
+}
+
+type BaseStationReportRecord struct {
+	Type              string                `json:"type"`
+	T                 int64                 `json:"t"`
+	BaseStationReport ais.BaseStationReport `json:"baseStationReport"`
+}
+
+func (record *BaseStationReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *BaseStationReportRecord) GetPacket() ais.Packet {
+	return record.BaseStationReport
+}
+
+type AidsToNavigationReportRecord struct {
+	Type                   string                     `json:"type"`
+	T                      int64                      `json:"t"`
+	AidsToNavigationReport ais.AidsToNavigationReport `json:"aidsToNavigationReport"`
+}
+
+func (record *AidsToNavigationReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *AidsToNavigationReportRecord) GetPacket() ais.Packet {
+	return record.AidsToNavigationReport
+}
+
+type StandardSearchAndRescueAircraftReportRecord struct {
+	Type                                  string                                    `json:"type"`
+	T                                     int64                                     `json:"t"`
+	StandardSearchAndRescueAircraftReport ais.StandardSearchAndRescueAircraftReport `json:"standardSearchAndRescueAircraftReport"`
+}
+
+func (record *StandardSearchAndRescueAircraftReportRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *StandardSearchAndRescueAircraftReportRecord) GetPacket() ais.Packet {
+	return record.StandardSearchAndRescueAircraftReport
+}
+
+type LongRangeAisBroadcastMessageRecord struct {
+	Type                         string                           `json:"type"`
+	T                            int64                            `json:"t"`
+	LongRangeAisBroadcastMessage ais.LongRangeAisBroadcastMessage `json:"longRangeAisBroadcastMessage"`
+}
+
+func (record *LongRangeAisBroadcastMessageRecord) GetTimestamp() int64 {
+	return record.T
+}
+
+func (record *LongRangeAisBroadcastMessageRecord) GetPacket() ais.Packet {
+	return record.LongRangeAisBroadcastMessage
+}
+
 func doAisView(ctx context.Context, cmd *cli.Command) error {
 	logFile := cmd.StringArg(inputFileArg.Name)
 	if logFile == "" {
@@ -55,6 +163,8 @@ func doAisView(ctx context.Context, cmd *cli.Command) error {
 	listenAddr := cmd.String(listenAddrFlag.Name)
 	playbackSpeed := cmd.Float64(playbackSpeedFlag.Name)
 	playbackUpdatePeriod := cmd.Duration(playbackUpdatePeriodFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+	raw := cmd.Bool(rawFlag.Name)
 	collectionPeriodInMs := (time.Duration(playbackUpdatePeriod.Seconds()*playbackSpeed) * time.Second).Milliseconds()
 
 	uiFs, err := fs.Sub(resources.UIFs, "gen/ui")
@@ -121,6 +231,7 @@ func doAisView(ctx context.Context, cmd *cli.Command) error {
 
 		loggerRecordReader := format.NewLoggerRecordReader(f)
 		aisRecordReader := format.NewAISRecordReader(loggerRecordReader, true)
+		aisRecordReader.SetDedupeWindow(dedupeWindow)
 
 		var records []PlaybackRecord
 		for {
@@ -140,7 +251,7 @@ func doAisView(ctx context.Context, cmd *cli.Command) error {
 				tCurrent := aisRecord.Timestamp
 				dt := tCurrent - tFirstInBatch
 				if dt > collectionPeriodInMs {
-					err = wsjson.Write(context.Background(), c, records)
+					err = writePlaybackRecords(c, records, raw)
 					if err != nil {
 						slog.Warn("error writing playback records",
 							slog.Any("err", err),
@@ -168,10 +279,62 @@ func doAisView(ctx context.Context, cmd *cli.Command) error {
 					ShipStaticData: packet,
 				}
 				records = append(records, &record)
+			case ais.StandardClassBPositionReport:
+				record := StandardClassBPositionReportRecord{
+					Type:                         "standardClassBPositionReport",
+					T:                            aisRecord.Timestamp,
+					StandardClassBPositionReport: packet,
+				}
+				records = append(records, &record)
+			case ais.ExtendedClassBPositionReport:
+				record := ExtendedClassBPositionReportRecord{
+					Type:                         "extendedClassBPositionReport",
+					T:                            aisRecord.Timestamp,
+					ExtendedClassBPositionReport: packet,
+				}
+				records = append(records, &record)
+			case ais.StaticDataReport:
+				record := StaticDataReportRecord{
+					Type:             "staticDataReport",
+					T:                aisRecord.Timestamp,
+					StaticDataReport: packet,
+				}
+				records = append(records, &record)
+			case ais.BaseStationReport:
+				if packet.MessageID != 4 {
+					break
+				}
+				record := BaseStationReportRecord{
+					Type:              "baseStationReport",
+					T:                 aisRecord.Timestamp,
+					BaseStationReport: packet,
+				}
+				records = append(records, &record)
+			case ais.AidsToNavigationReport:
+				record := AidsToNavigationReportRecord{
+					Type:                   "aidsToNavigationReport",
+					T:                      aisRecord.Timestamp,
+					AidsToNavigationReport: packet,
+				}
+				records = append(records, &record)
+			case ais.StandardSearchAndRescueAircraftReport:
+				record := StandardSearchAndRescueAircraftReportRecord{
+					Type:                                  "standardSearchAndRescueAircraftReport",
+					T:                                     aisRecord.Timestamp,
+					StandardSearchAndRescueAircraftReport: packet,
+				}
+				records = append(records, &record)
+			case ais.LongRangeAisBroadcastMessage:
+				record := LongRangeAisBroadcastMessageRecord{
+					Type:                         "longRangeAisBroadcastMessage",
+					T:                            aisRecord.Timestamp,
+					LongRangeAisBroadcastMessage: packet,
+				}
+				records = append(records, &record)
 			}
 		}
 		if len(records) > 0 {
-			err = wsjson.Write(context.Background(), c, records)
+			err = writePlaybackRecords(c, records, raw)
 			if err != nil {
 				slog.Warn("error writing playback records",
 					slog.Any("err", err),
@@ -200,3 +363,23 @@ func doAisView(ctx context.Context, cmd *cli.Command) error {
 	fmt.Printf("URL: http://%s\n", httpListener.Addr().String())
 	return http.Serve(httpListener, nil)
 }
+
+// writePlaybackRecords sends a batch of playback records. AIS "not available" values are sent as null unless raw is set.
+func writePlaybackRecords(c *websocket.Conn, records []PlaybackRecord, raw bool) error {
+	if raw {
+		return wsjson.Write(context.Background(), c, records)
+	}
+	var messages []json.RawMessage
+	for _, record := range records {
+		jsonBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		jsonBytes, err = format.NullJSONFields(jsonBytes, format.AISNotAvailableFields(record.GetPacket()))
+		if err != nil {
+			return err
+		}
+		messages = append(messages, jsonBytes)
+	}
+	return wsjson.Write(context.Background(), c, messages)
+}
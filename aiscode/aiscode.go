@@ -0,0 +1,134 @@
+// Package aiscode decodes AIS identifiers and codes into human-readable form: the flag state and station class of an
+// MMSI, ship types and navigational statuses. The lookup tables are embedded CSV files.
+package aiscode
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+)
+
+var (
+	//go:embed mid.csv
+	midCsv []byte
+	//go:embed ship_types.csv
+	shipTypesCsv []byte
+	//go:embed navigational_status.csv
+	navigationalStatusCsv []byte
+
+	countryMap            = make(map[int]Country)
+	shipTypeMap           = make(map[int]string)
+	navigationalStatusMap = make(map[int]string)
+)
+
+// Country is the country or geographical area to which a Maritime Identification Digits (MID) value is allocated.
+type Country struct {
+	// Code is the ISO 3166-1 alpha-2 code.
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type StationClass string
+
+const (
+	StationClassShip            StationClass = "ship"
+	StationClassGroupOfShips    StationClass = "groupOfShips"
+	StationClassBaseStation     StationClass = "baseStation"
+	StationClassAircraft        StationClass = "aircraft"
+	StationClassAidToNavigation StationClass = "aidToNavigation"
+	StationClassAuxiliaryCraft  StationClass = "auxiliaryCraft"
+	StationClassHandheld        StationClass = "handheld"
+	StationClassSART            StationClass = "sart"
+	StationClassMOB             StationClass = "mob"
+	StationClassEPIRB           StationClass = "epirb"
+	StationClassUnknown         StationClass = "unknown"
+)
+
+func init() {
+	mustLoadTable(midCsv, func(code int, record []string) {
+		countryMap[code] = Country{
+			Code: record[1],
+			Name: record[2],
+		}
+	})
+	mustLoadTable(shipTypesCsv, func(code int, record []string) {
+		shipTypeMap[code] = record[1]
+	})
+	mustLoadTable(navigationalStatusCsv, func(code int, record []string) {
+		navigationalStatusMap[code] = record[1]
+	})
+}
+
+// mustLoadTable parses an embedded table whose first column is a numeric code. The first row is a header.
+func mustLoadTable(data []byte, add func(code int, record []string)) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(err)
+	}
+	for _, record := range records[1:] {
+		code, err := strconv.Atoi(record[0])
+		if err != nil {
+			panic(err)
+		}
+		add(code, record)
+	}
+}
+
+// Classify returns the station class of an MMSI, as given by its format (ITU-R M.585), and the MID embedded in it. mid
+// is 0 if the MMSI does not embed one.
+func Classify(mmsi uint32) (stationClass StationClass, mid int) {
+	if mmsi > 999999999 {
+		return StationClassUnknown, 0
+	}
+	s := fmt.Sprintf("%09d", mmsi)
+	switch {
+	case (s[0] >= '2') && (s[0] <= '7'):
+		return StationClassShip, parseMID(s[0:3])
+	case s[:2] == "00":
+		return StationClassBaseStation, parseMID(s[2:5])
+	case s[0] == '0':
+		return StationClassGroupOfShips, parseMID(s[1:4])
+	case s[:3] == "111":
+		return StationClassAircraft, parseMID(s[3:6])
+	case s[0] == '8':
+		return StationClassHandheld, parseMID(s[1:4])
+	case s[:3] == "970":
+		return StationClassSART, 0
+	case s[:3] == "972":
+		return StationClassMOB, 0
+	case s[:3] == "974":
+		return StationClassEPIRB, 0
+	case s[:2] == "98":
+		return StationClassAuxiliaryCraft, parseMID(s[2:5])
+	case s[:2] == "99":
+		return StationClassAidToNavigation, parseMID(s[2:5])
+	}
+	return StationClassUnknown, 0
+}
+
+func parseMID(s string) int {
+	mid, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return mid
+}
+
+// Flag returns the country whose MID is embedded in an MMSI.
+func Flag(mmsi uint32) (Country, bool) {
+	_, mid := Classify(mmsi)
+	country, ok := countryMap[mid]
+	return country, ok
+}
+
+// ShipTypeText returns the description of a ship type code, or "" if the code is out of range.
+func ShipTypeText(shipType uint8) string {
+	return shipTypeMap[int(shipType)]
+}
+
+// NavigationalStatusText returns the description of a navigational status code, or "" if the code is out of range.
+func NavigationalStatusText(navigationalStatus uint8) string {
+	return navigationalStatusMap[int(navigationalStatus)]
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/aiscode"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	anomalyImpliedSpeed   = "implied-speed"
+	anomalyDuplicateMMSI  = "duplicate-mmsi"
+	anomalyInvalidMMSI    = "invalid-mmsi"
+	anomalyStaticFlipFlop = "static-flip-flop"
+	anomalyStaleTimestamp = "stale-timestamp"
+	anomalyUndecodable    = "undecodable-payload"
+)
+
+// anomalyMinJumpDistance is the distance, in metres, below which consecutive positions are never reported as
+// exceeding the maximum speed, as receive timestamps are too coarse to measure the speed over short distances.
+const anomalyMinJumpDistance = 0.5 * geo.MetresPerNauticalMile
+
+// anomalyMaxTracks is the maximum number of concurrent tracks kept for an MMSI.
+const anomalyMaxTracks = 4
+
+// anomalyFlipFlopFields are the static data fields that are not expected to return to a previous value.
+var anomalyFlipFlopFields = []string{"name", "callSign", "imo", "shipType", "dimension"}
+
+// Anomaly is a kind of anomaly observed for an MMSI. Message, Latitude and Longitude describe the first occurrence;
+// Latitude and Longitude are nil if it has no position.
+type Anomaly struct {
+	Kind      string    `json:"kind"`
+	MMSI      uint32    `json:"mmsi"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Count     int       `json:"count"`
+	Message   string    `json:"message"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+}
+
+// anomalyTrack is a sequence of positions of an MMSI that is consistent with a single vessel.
+type anomalyTrack struct {
+	timestamp int64
+	point     geo.Point
+}
+
+type anomalyDetector struct {
+	maxSpeed     float64
+	maxStaleness time.Duration
+	window       time.Duration
+	trackMap     map[uint32][]*anomalyTrack
+	valueMap     map[uint32]map[string][]any
+	anomalyMap   map[string]*Anomaly
+}
+
+func newAnomalyDetector(maxSpeed float64, maxStaleness time.Duration, window time.Duration) *anomalyDetector {
+	return &anomalyDetector{
+		maxSpeed:     maxSpeed,
+		maxStaleness: maxStaleness,
+		window:       window,
+		trackMap:     make(map[uint32][]*anomalyTrack),
+		valueMap:     make(map[uint32]map[string][]any),
+		anomalyMap:   make(map[string]*Anomaly),
+	}
+}
+
+// report records an occurrence of an anomaly. point is nil if the occurrence has no position.
+func (detector *anomalyDetector) report(kind string, mmsi uint32, timestamp int64, point *geo.Point, message string) {
+	t := time.UnixMilli(timestamp).UTC()
+	key := fmt.Sprintf("%s/%d", kind, mmsi)
+	anomaly, ok := detector.anomalyMap[key]
+	if ok {
+		anomaly.Count++
+		if t.After(anomaly.LastSeen) {
+			anomaly.LastSeen = t
+		}
+		return
+	}
+	anomaly = &Anomaly{
+		Kind:      kind,
+		MMSI:      mmsi,
+		FirstSeen: t,
+		LastSeen:  t,
+		Count:     1,
+		Message:   message,
+	}
+	if point != nil {
+		anomaly.Latitude = &point.Latitude
+		anomaly.Longitude = &point.Longitude
+	}
+	detector.anomalyMap[key] = anomaly
+}
+
+func (detector *anomalyDetector) add(record *format.AISRecord) {
+	packet := record.AIS.Packet
+	header := record.Header()
+	mmsi := header.UserID
+	if packet == nil {
+		detector.report(anomalyUndecodable, mmsi, record.Timestamp, nil,
+			fmt.Sprintf("type %d payload of %d bits could not be decoded", header.MessageID, len(record.AIS.Payload)))
+		return
+	}
+	var point *geo.Point
+	latitude, longitude, ok := format.AISPosition(packet)
+	if ok {
+		point = &geo.Point{
+			Longitude: longitude,
+			Latitude:  latitude,
+		}
+	}
+
+	detector.checkMMSI(record, point)
+	detector.checkTimestamp(record, point)
+	detector.checkStaticData(record)
+	trackPoint, ok := vesselTrackPointOf(record)
+	if ok {
+		detector.checkPosition(mmsi, trackPoint)
+	}
+}
+
+// checkMMSI checks that the MMSI is well-formed (ITU-R M.585), that its MID is allocated, and that the station class
+// matches the message.
+func (detector *anomalyDetector) checkMMSI(record *format.AISRecord, point *geo.Point) {
+	packet := record.AIS.Packet
+	mmsi := record.Header().UserID
+	stationClass, mid := aiscode.Classify(mmsi)
+	if stationClass == aiscode.StationClassUnknown {
+		detector.report(anomalyInvalidMMSI, mmsi, record.Timestamp, point,
+			fmt.Sprintf("MMSI %09d is out of range or reserved", mmsi))
+		return
+	}
+	_, ok := aiscode.Flag(mmsi)
+	if (mid != 0) && !ok {
+		detector.report(anomalyInvalidMMSI, mmsi, record.Timestamp, point,
+			fmt.Sprintf("MMSI %09d embeds an unallocated MID %03d", mmsi, mid))
+		return
+	}
+	_, _, isVessel := vesselFields(packet)
+	if isVessel && ((stationClass == aiscode.StationClassBaseStation) ||
+		(stationClass == aiscode.StationClassAidToNavigation)) {
+		detector.report(anomalyInvalidMMSI, mmsi, record.Timestamp, point,
+			fmt.Sprintf("MMSI %09d of a %s sent a type %d vessel message", mmsi, stationClass, record.Header().MessageID))
+	}
+}
+
+// checkTimestamp compares the UTC second reported by a message with the receive time.
+func (detector *anomalyDetector) checkTimestamp(record *format.AISRecord, point *geo.Point) {
+	var second uint8
+	switch report := record.AIS.Packet.(type) {
+	case ais.PositionReport:
+		second = report.Timestamp
+	case ais.StandardSearchAndRescueAircraftReport:
+		second = report.Timestamp
+	case ais.StandardClassBPositionReport:
+		second = report.Timestamp
+	case ais.ExtendedClassBPositionReport:
+		second = report.Timestamp
+	case ais.AidsToNavigationReport:
+		second = report.Timestamp
+	default:
+		return
+	}
+	// 60 and above are "not available" or positioning system status values
+	if second >= 60 {
+		return
+	}
+	received := time.UnixMilli(record.Timestamp).UTC()
+	offset := received.Sub(received.Truncate(time.Minute).Add(time.Duration(second) * time.Second))
+	// The offset is only known modulo one minute
+	if offset > 30*time.Second {
+		offset -= time.Minute
+	}
+	relation := "behind"
+	if offset < 0 {
+		offset, relation = -offset, "ahead of"
+	}
+	if offset > detector.maxStaleness {
+		detector.report(anomalyStaleTimestamp, record.Header().UserID, record.Timestamp, point,
+			fmt.Sprintf("UTC second %d is %s %s the receive time %s", second, offset.Round(time.Millisecond), relation,
+				received.Format(time.RFC3339Nano)))
+	}
+}
+
+// checkStaticData reports static data fields that return to a value they had before.
+func (detector *anomalyDetector) checkStaticData(record *format.AISRecord) {
+	_, fields, ok := vesselFields(record.AIS.Packet)
+	if !ok || (len(fields) == 0) {
+		return
+	}
+	mmsi := record.Header().UserID
+	values, ok := detector.valueMap[mmsi]
+	if !ok {
+		values = make(map[string][]any)
+		detector.valueMap[mmsi] = values
+	}
+	for _, field := range fields {
+		if !slices.Contains(anomalyFlipFlopFields, field.name) {
+			continue
+		}
+		// The last value is the current one
+		history := values[field.name]
+		if (len(history) > 0) && (history[len(history)-1] == field.value) {
+			continue
+		}
+		i := slices.Index(history, field.value)
+		if i >= 0 {
+			detector.report(anomalyStaticFlipFlop, mmsi, record.Timestamp, nil,
+				fmt.Sprintf("%s changed from %s back to %s", field.name,
+					formatVesselValue(history[len(history)-1]), formatVesselValue(field.value)))
+			history = slices.Delete(history, i, i+1)
+		}
+		values[field.name] = append(history, field.value)
+	}
+}
+
+// checkPosition assigns a position to a track of the MMSI that it is consistent with. A position that is not
+// consistent with the latest track starts a new one, and is reported as exceeding the maximum speed. A position that
+// is consistent with an earlier track means that several vessels are reporting with the MMSI.
+func (detector *anomalyDetector) checkPosition(mmsi uint32, trackPoint vesselTrackPoint) {
+	window := detector.window.Milliseconds()
+	tracks := slices.DeleteFunc(detector.trackMap[mmsi], func(track *anomalyTrack) bool {
+		return trackPoint.timestamp-track.timestamp > window
+	})
+	defer func() {
+		detector.trackMap[mmsi] = tracks
+	}()
+	if len(tracks) == 0 {
+		tracks = append(tracks, &anomalyTrack{
+			timestamp: trackPoint.timestamp,
+			point:     trackPoint.point,
+		})
+		return
+	}
+
+	// Tracks are ordered by their last update
+	latest := tracks[len(tracks)-1]
+	if trackPoint.timestamp < latest.timestamp {
+		return
+	}
+	update := func(i int) {
+		track := tracks[i]
+		track.timestamp = trackPoint.timestamp
+		track.point = trackPoint.point
+		tracks = append(slices.Delete(tracks, i, i+1), track)
+	}
+	distance, speed := detector.impliedSpeed(latest, trackPoint)
+	if (distance < anomalyMinJumpDistance) || (speed <= detector.maxSpeed) {
+		update(len(tracks) - 1)
+		return
+	}
+	for i := len(tracks) - 2; i >= 0; i-- {
+		otherDistance, otherSpeed := detector.impliedSpeed(tracks[i], trackPoint)
+		if (otherDistance < anomalyMinJumpDistance) || (otherSpeed <= detector.maxSpeed) {
+			detector.report(anomalyDuplicateMMSI, mmsi, trackPoint.timestamp, &trackPoint.point,
+				fmt.Sprintf("reporting from %.1f nm apart within %s", distance/geo.MetresPerNauticalMile,
+					time.Duration(trackPoint.timestamp-latest.timestamp)*time.Millisecond))
+			update(i)
+			return
+		}
+	}
+	detector.report(anomalyImpliedSpeed, mmsi, trackPoint.timestamp, &trackPoint.point,
+		fmt.Sprintf("moved %.1f nm in %s, an implied speed of %.0f kn", distance/geo.MetresPerNauticalMile,
+			time.Duration(trackPoint.timestamp-latest.timestamp)*time.Millisecond, speed))
+	tracks = append(tracks, &anomalyTrack{
+		timestamp: trackPoint.timestamp,
+		point:     trackPoint.point,
+	})
+	if len(tracks) > anomalyMaxTracks {
+		tracks = tracks[1:]
+	}
+}
+
+// impliedSpeed returns the distance, in metres, and the speed, in knots, implied by moving from the last position of a
+// track to trackPoint. Timestamps less than one second apart are treated as one second apart.
+func (detector *anomalyDetector) impliedSpeed(track *anomalyTrack, trackPoint vesselTrackPoint) (distance float64, speed float64) {
+	distance = geo.Distance(track.point, trackPoint.point)
+	seconds := max(float64(trackPoint.timestamp-track.timestamp)/1000, 1)
+	return distance, distance / geo.MetresPerNauticalMile / (seconds / 3600)
+}
+
+// anomalies returns the anomalies, ordered by first occurrence.
+func (detector *anomalyDetector) anomalies() []*Anomaly {
+	var anomalies []*Anomaly
+	for _, anomaly := range detector.anomalyMap {
+		anomalies = append(anomalies, anomaly)
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		if !anomalies[i].FirstSeen.Equal(anomalies[j].FirstSeen) {
+			return anomalies[i].FirstSeen.Before(anomalies[j].FirstSeen)
+		}
+		if anomalies[i].MMSI != anomalies[j].MMSI {
+			return anomalies[i].MMSI < anomalies[j].MMSI
+		}
+		return anomalies[i].Kind < anomalies[j].Kind
+	})
+	return anomalies
+}
+
+func doAisAnomalies(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+	outputFormat := cmd.String(jsonCsvFormatFlag.Name)
+
+	detector := newAnomalyDetector(cmd.Float64(maxSpeedFlag.Name), cmd.Duration(maxStalenessFlag.Name),
+		cmd.Duration(duplicateWindowFlag.Name))
+
+	const keepUndecodable = true
+	err := readAISRecords(inputFiles, aisRecordOptions{
+		MMSIs:           mmsis,
+		DedupeWindow:    dedupeWindow,
+		KeepUndecodable: keepUndecodable,
+	}, func(aisRecord *format.AISRecord) error {
+		detector.add(aisRecord)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	anomalies := detector.anomalies()
+	switch outputFormat {
+	case "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		if anomalies == nil {
+			anomalies = []*Anomaly{}
+		}
+		return jsonEncoder.Encode(anomalies)
+
+	default:
+		return writeAnomaliesCsv(os.Stdout, anomalies)
+	}
+}
+
+func writeAnomaliesCsv(w io.Writer, anomalies []*Anomaly) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"KIND", "MMSI", "FIRST SEEN", "LAST SEEN", "COUNT", "MESSAGE", "LATITUDE", "LONGITUDE",
+	})
+	if err != nil {
+		return err
+	}
+	for _, anomaly := range anomalies {
+		cells := []string{
+			anomaly.Kind,
+			strconv.FormatInt(int64(anomaly.MMSI), 10),
+			anomaly.FirstSeen.Format(time.RFC3339Nano),
+			anomaly.LastSeen.Format(time.RFC3339Nano),
+			strconv.Itoa(anomaly.Count),
+			anomaly.Message,
+			formatCoordinate(anomaly.Latitude),
+			formatCoordinate(anomaly.Longitude),
+		}
+		err = csvWriter.Write(cells)
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func formatCoordinate(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.5f", *value)
+}
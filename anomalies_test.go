@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+)
+
+func TestAnomalyDetectorCheckPosition(t *testing.T) {
+	type position struct {
+		second    int64
+		longitude float64
+	}
+	tests := []struct {
+		name      string
+		positions []position
+		want      []string
+	}{
+		{"steady", []position{{0, 0}, {60, 0.01}, {120, 0.02}}, nil},
+		{"jitter under the minimum jump", []position{{0, 0}, {1, 0.001}, {2, 0}}, nil},
+		{"jump", []position{{0, 0}, {60, 1}}, []string{anomalyImpliedSpeed}},
+		{"jump then steady", []position{{0, 0}, {60, 1}, {120, 1.01}}, []string{anomalyImpliedSpeed}},
+		{"alternating", []position{{0, 0}, {10, 1}, {20, 0.0001}, {30, 1.0001}}, []string{anomalyDuplicateMMSI, anomalyImpliedSpeed}},
+		{"older position ignored", []position{{60, 0}, {0, 1}}, nil},
+		{"jump after the window", []position{{0, 0}, {11 * 60, 1}}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detector := newAnomalyDetector(50, 10*time.Second, 10*time.Minute)
+			for _, p := range test.positions {
+				detector.checkPosition(123456789, vesselTrackPoint{
+					timestamp: p.second * 1000,
+					point:     geo.Point{Longitude: p.longitude},
+				})
+			}
+			var got []string
+			for _, anomaly := range detector.anomalies() {
+				got = append(got, anomaly.Kind)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("anomalies = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAnomalyDetectorUndecodable(t *testing.T) {
+	vdmPacket, err := format.NewAISDecoder().ParseSentence("!AIVDM,1,1,,A,13u?etP00,0*2F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := newAnomalyDetector(50, 10*time.Second, 10*time.Minute)
+	detector.add(&format.AISRecord{
+		Timestamp: 1000,
+		AIS:       vdmPacket,
+	})
+	anomalies := detector.anomalies()
+	if (len(anomalies) != 1) || (anomalies[0].Kind != anomalyUndecodable) || (anomalies[0].MMSI != 265547250) {
+		t.Fatalf("anomalies = %+v, want one %s anomaly of MMSI 265547250", anomalies, anomalyUndecodable)
+	}
+}
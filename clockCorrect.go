@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	nmea "github.com/adrianmo/go-nmea"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+// clockSample is a single observation of the offset (true UTC - logged timestamp) in milliseconds.
+type clockSample struct {
+	timestamp int64
+	offset    int64
+}
+
+// clockKnot is the median offset of the samples within a window, placed at the median logged timestamp.
+type clockKnot struct {
+	timestamp int64
+	offset    int64
+	samples   int
+}
+
+type clockCorrection []clockKnot
+
+// offsetAt interpolates the offset linearly between knots, and holds it constant beyond the first and last knots.
+func (correction clockCorrection) offsetAt(timestamp int64) int64 {
+	i := sort.Search(len(correction), func(i int) bool {
+		return correction[i].timestamp >= timestamp
+	})
+	if i == 0 {
+		return correction[0].offset
+	}
+	if i == len(correction) {
+		return correction[len(correction)-1].offset
+	}
+	a := correction[i-1]
+	b := correction[i]
+	f := float64(timestamp-a.timestamp) / float64(b.timestamp-a.timestamp)
+	return a.offset + int64(math.Round(f*float64(b.offset-a.offset)))
+}
+
+// drift returns the least-squares drift rate of the offset, in seconds per day.
+func (correction clockCorrection) drift() float64 {
+	if len(correction) < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for _, knot := range correction {
+		x := float64(knot.timestamp-correction[0].timestamp) / float64(24*time.Hour/time.Millisecond)
+		y := float64(knot.offset) / 1000
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(correction))
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / d
+}
+
+func doClockCorrect(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputFile := cmd.String(outputFlag.Name)
+	window := cmd.Duration(windowFlag.Name)
+	references := cmd.StringSlice(timeReferenceFlag.Name)
+
+	useGNSS := slices.Contains(references, "gnss")
+	useAIS := slices.Contains(references, "ais")
+
+	samples, err := collectClockSamples(inputFiles, useGNSS, useAIS)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("no time references found")
+	}
+	correction := newClockCorrection(samples, window)
+
+	f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+	if err != nil {
+		return err
+	}
+	defer func(f io.WriteCloser) {
+		_ = f.Close()
+	}(f)
+	if filepath.Ext(outputFile1) != ".jsonl" {
+		return fmt.Errorf("unsupported file extension")
+	}
+	loggerRecordWriter := format.NewLoggerRecordWriter(f)
+	defer func(loggerRecordWriter *format.LoggerRecordWriter) {
+		_ = loggerRecordWriter.Close()
+	}(loggerRecordWriter)
+
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				continue
+			}
+			return err
+		}
+		if loggerRecord == nil {
+			break
+		}
+		loggerRecord.Timestamp += correction.offsetAt(loggerRecord.Timestamp)
+		err = loggerRecordWriter.WriteLoggerRecord(loggerRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printClockCorrection(os.Stdout, correction)
+}
+
+func collectClockSamples(inputFiles []string, useGNSS bool, useAIS bool) ([]clockSample, error) {
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	aisDecoder := format.NewAISDecoder()
+
+	var samples []clockSample
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				continue
+			}
+			return nil, err
+		}
+		if loggerRecord == nil {
+			break
+		}
+
+		sentenceInfo, err := format.ParseSentenceInfo(loggerRecord.NMEA)
+		if (err != nil) || !sentenceInfo.ChecksumValid {
+			continue
+		}
+
+		var t time.Time
+		if sentenceInfo.Encapsulated {
+			if !useAIS {
+				continue
+			}
+			vdmPacket, err := aisDecoder.ParseSentence(loggerRecord.NMEA)
+			if (err != nil) || (vdmPacket == nil) {
+				continue
+			}
+			t = aisReferenceTime(vdmPacket.Packet)
+		} else {
+			if !useGNSS || ((sentenceInfo.Type != nmea.TypeRMC) && (sentenceInfo.Type != nmea.TypeZDA)) {
+				continue
+			}
+			sentence, err := nmea.Parse(loggerRecord.NMEA)
+			if err != nil {
+				continue
+			}
+			t = gnssReferenceTime(sentence)
+		}
+		if t.IsZero() {
+			continue
+		}
+		samples = append(samples, clockSample{
+			timestamp: loggerRecord.Timestamp,
+			offset:    t.UnixMilli() - loggerRecord.Timestamp,
+		})
+	}
+	return samples, nil
+}
+
+func gnssReferenceTime(sentence nmea.Sentence) time.Time {
+	switch s := sentence.(type) {
+	case nmea.RMC:
+		if (s.Validity != nmea.ValidRMC) || !s.Date.Valid || !s.Time.Valid {
+			return time.Time{}
+		}
+		return time.Date(format.NMEAYear(s.Date.YY), time.Month(s.Date.MM), s.Date.DD,
+			s.Time.Hour, s.Time.Minute, s.Time.Second, s.Time.Millisecond*int(time.Millisecond), time.UTC)
+	case nmea.ZDA:
+		if !s.Time.Valid || (s.Year == 0) {
+			return time.Time{}
+		}
+		return time.Date(int(s.Year), time.Month(s.Month), int(s.Day),
+			s.Time.Hour, s.Time.Minute, s.Time.Second, s.Time.Millisecond*int(time.Millisecond), time.UTC)
+	}
+	return time.Time{}
+}
+
+func aisReferenceTime(packet ais.Packet) time.Time {
+	report, ok := packet.(ais.BaseStationReport)
+	if !ok || (report.MessageID != 4) {
+		return time.Time{}
+	}
+	if (report.UtcYear == 0) || (report.UtcMonth == 0) || (report.UtcMonth > 12) || (report.UtcDay == 0) ||
+		(report.UtcHour > 23) || (report.UtcMinute > 59) || (report.UtcSecond > 59) {
+		return time.Time{}
+	}
+	return time.Date(int(report.UtcYear), time.Month(report.UtcMonth), int(report.UtcDay),
+		int(report.UtcHour), int(report.UtcMinute), int(report.UtcSecond), 0, time.UTC)
+}
+
+func newClockCorrection(samples []clockSample, window time.Duration) clockCorrection {
+	windowMap := make(map[int64][]clockSample)
+	for _, sample := range samples {
+		key := sample.timestamp / window.Milliseconds()
+		windowMap[key] = append(windowMap[key], sample)
+	}
+
+	var correction clockCorrection
+	for _, windowSamples := range windowMap {
+		timestamps := make([]int64, len(windowSamples))
+		offsets := make([]int64, len(windowSamples))
+		for i, sample := range windowSamples {
+			timestamps[i] = sample.timestamp
+			offsets[i] = sample.offset
+		}
+		slices.Sort(timestamps)
+		slices.Sort(offsets)
+		correction = append(correction, clockKnot{
+			timestamp: timestamps[len(timestamps)/2],
+			offset:    offsets[len(offsets)/2],
+			samples:   len(windowSamples),
+		})
+	}
+	sort.Slice(correction, func(i, j int) bool {
+		return correction[i].timestamp < correction[j].timestamp
+	})
+	return correction
+}
+
+func printClockCorrection(w io.Writer, correction clockCorrection) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "LOGGED TIME (UTC)\tOFFSET\tSAMPLES\n")
+	for _, knot := range correction {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\n",
+			time.UnixMilli(knot.timestamp).UTC().Format(time.RFC3339),
+			time.Duration(knot.offset)*time.Millisecond,
+			knot.samples,
+		)
+	}
+	_, _ = fmt.Fprintf(tw, "\nEstimated drift:\t%.3f s/day\n", correction.drift())
+	return tw.Flush()
+}
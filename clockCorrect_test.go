@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestClockCorrectionOffsetAt(t *testing.T) {
+	correction := clockCorrection{
+		{timestamp: 1000, offset: 100},
+		{timestamp: 2000, offset: 200},
+		{timestamp: 4000, offset: 0},
+	}
+	tests := []struct {
+		name      string
+		timestamp int64
+		want      int64
+	}{
+		{"before first knot", 0, 100},
+		{"at first knot", 1000, 100},
+		{"between knots", 1500, 150},
+		{"at inner knot", 2000, 200},
+		{"decreasing", 3000, 100},
+		{"at last knot", 4000, 0},
+		{"after last knot", 10000, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := correction.offsetAt(test.timestamp)
+			if got != test.want {
+				t.Errorf("offsetAt(%d) = %d, want %d", test.timestamp, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewClockCorrection(t *testing.T) {
+	samples := []clockSample{
+		{timestamp: 1000, offset: 30},
+		{timestamp: 2000, offset: 10},
+		{timestamp: 3000, offset: 20},
+		{timestamp: 61000, offset: -5},
+	}
+	correction := newClockCorrection(samples, time.Minute)
+	want := clockCorrection{
+		{timestamp: 2000, offset: 20, samples: 3},
+		{timestamp: 61000, offset: -5, samples: 1},
+	}
+	if len(correction) != len(want) {
+		t.Fatalf("got %d knots, want %d", len(correction), len(want))
+	}
+	for i := range want {
+		if correction[i] != want[i] {
+			t.Errorf("knot %d = %+v, want %+v", i, correction[i], want[i])
+		}
+	}
+}
+
+func TestClockCorrectionDrift(t *testing.T) {
+	day := (24 * time.Hour).Milliseconds()
+	tests := []struct {
+		name       string
+		correction clockCorrection
+		want       float64
+	}{
+		{"single knot", clockCorrection{{timestamp: 0, offset: 1000}}, 0},
+		{"constant offset", clockCorrection{{timestamp: 0, offset: 500}, {timestamp: day, offset: 500}}, 0},
+		{"one second per day", clockCorrection{{timestamp: 0, offset: 0}, {timestamp: day, offset: 1000}, {timestamp: 2 * day, offset: 2000}}, 1},
+		{"same timestamp", clockCorrection{{timestamp: 0, offset: 0}, {timestamp: 0, offset: 1000}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.correction.drift()
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("drift() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+// coverageMaxFixAge is the maximum time between a message and the GNSS fix used as the receiver position.
+const coverageMaxFixAge = 5 * time.Minute
+
+// Coverage is the reception coverage of a station, i.e. of the records with a given source. Unlocated counts the
+// positions skipped as the receiver position was unknown. Distances are in nautical miles.
+type Coverage struct {
+	Source    string
+	Receiver  geo.Point
+	Messages  int
+	Positions int
+	Unlocated int
+	Sectors   []*CoverageSector
+	Bands     []*CoverageBand
+	Intervals []*CoverageInterval
+}
+
+// CoverageSector holds the ranges of the positions received from a bearing sector. Ranges holds the range at each of
+// the requested percentiles.
+type CoverageSector struct {
+	From      float64
+	To        float64
+	Positions int
+	MaxRange  float64
+	Ranges    []float64
+}
+
+// CoverageBand counts the positions received from a distance band.
+type CoverageBand struct {
+	From      float64
+	To        float64
+	Positions int
+	Vessels   int
+}
+
+// CoverageInterval counts the messages of each type received in a time window.
+type CoverageInterval struct {
+	Start        time.Time
+	MessageTypes []CoverageMessageType
+}
+
+type CoverageMessageType struct {
+	Type     uint8
+	Messages int
+	Vessels  int
+}
+
+// coverageFix is a receiver position from a GNSS fix in the log.
+type coverageFix struct {
+	timestamp int64
+	point     geo.Point
+}
+
+// coveragePosition is a position received by a station. Positions are located once the GNSS fixes of the whole log
+// are known.
+type coveragePosition struct {
+	timestamp int64
+	mmsi      uint32
+	point     geo.Point
+}
+
+type coverageStation struct {
+	coverage       *Coverage
+	positions      []coveragePosition
+	receiverSum    geo.Point
+	distances      [][]float64
+	bandMap        map[int]*CoverageBand
+	bandVessels    map[int]map[uint32]bool
+	intervalMap    map[int64]map[uint8]*CoverageMessageType
+	intervalVessel map[int64]map[uint8]map[uint32]bool
+}
+
+type coverageAnalyzer struct {
+	receivers   map[string]geo.Point
+	fixMap      map[string][]coverageFix
+	sectors     int
+	bandWidth   float64
+	interval    time.Duration
+	percentiles []float64
+	stationMap  map[string]*coverageStation
+}
+
+// parseCoverageReceivers parses receiver positions given as "[source=]latitude,longitude". A position without a source
+// applies to all sources. As slice flag values are split at commas, the values are rejoined before parsing.
+func parseCoverageReceivers(values []string) (map[string]geo.Point, error) {
+	receivers := make(map[string]geo.Point)
+	if len(values) == 0 {
+		return receivers, nil
+	}
+	parts := strings.Split(strings.Join(values, ","), ",")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid receiver positions: %s", strings.Join(values, ","))
+	}
+	for i := 0; i < len(parts); i += 2 {
+		source, latitudeString, ok := strings.Cut(parts[i], "=")
+		if !ok {
+			source, latitudeString = "*", parts[i]
+		}
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeString), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receiver position: %s,%s", parts[i], parts[i+1])
+		}
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[i+1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receiver position: %s,%s", parts[i], parts[i+1])
+		}
+		receivers[source] = geo.Point{
+			Longitude: longitude,
+			Latitude:  latitude,
+		}
+	}
+	return receivers, nil
+}
+
+// readCoverageFixes reads the valid GNSS fixes of a log, by source. Malformed records are skipped.
+func readCoverageFixes(reader io.Reader) (map[string][]coverageFix, error) {
+	gnssFixReader := format.NewGNSSFixReader(format.NewNMEARecordReader(format.NewLoggerRecordReader(reader)))
+	fixMap := make(map[string][]coverageFix)
+	for {
+		fix, err := gnssFixReader.ReadGNSSFix()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				continue
+			}
+			return nil, err
+		}
+		if fix == nil {
+			break
+		}
+		if !fix.Valid {
+			continue
+		}
+		fixMap[fix.Source] = append(fixMap[fix.Source], coverageFix{
+			timestamp: fix.Timestamp,
+			point: geo.Point{
+				Longitude: fix.Longitude,
+				Latitude:  fix.Latitude,
+			},
+		})
+	}
+	for _, fixes := range fixMap {
+		sort.SliceStable(fixes, func(i, j int) bool {
+			return fixes[i].timestamp < fixes[j].timestamp
+		})
+	}
+	return fixMap, nil
+}
+
+// receiver returns the position of the receiver of a record: the position given for its source, else the position
+// given for all sources, else the GNSS fix of the source nearest in time. GNSS fixes without a source are used for
+// sources that have none.
+func (analyzer *coverageAnalyzer) receiver(source string, timestamp int64) (geo.Point, bool) {
+	p, ok := analyzer.receivers[source]
+	if ok {
+		return p, true
+	}
+	p, ok = analyzer.receivers["*"]
+	if ok {
+		return p, true
+	}
+	fixes, ok := analyzer.fixMap[source]
+	if !ok {
+		fixes = analyzer.fixMap[""]
+	}
+	i := sort.Search(len(fixes), func(i int) bool {
+		return fixes[i].timestamp >= timestamp
+	})
+	var nearest *coverageFix
+	for _, j := range []int{i - 1, i} {
+		if (j < 0) || (j >= len(fixes)) {
+			continue
+		}
+		if (nearest == nil) || (absInt64(fixes[j].timestamp-timestamp) < absInt64(nearest.timestamp-timestamp)) {
+			nearest = &fixes[j]
+		}
+	}
+	if (nearest == nil) || (absInt64(nearest.timestamp-timestamp) > coverageMaxFixAge.Milliseconds()) {
+		return geo.Point{}, false
+	}
+	return nearest.point, true
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (analyzer *coverageAnalyzer) station(source string) *coverageStation {
+	station, ok := analyzer.stationMap[source]
+	if !ok {
+		station = &coverageStation{
+			coverage: &Coverage{
+				Source: source,
+			},
+			distances:      make([][]float64, analyzer.sectors),
+			bandMap:        make(map[int]*CoverageBand),
+			bandVessels:    make(map[int]map[uint32]bool),
+			intervalMap:    make(map[int64]map[uint8]*CoverageMessageType),
+			intervalVessel: make(map[int64]map[uint8]map[uint32]bool),
+		}
+		analyzer.stationMap[source] = station
+	}
+	return station
+}
+
+func (analyzer *coverageAnalyzer) add(record *format.AISRecord) {
+	source := ""
+	if len(record.Sources) > 0 {
+		source = record.Sources[0]
+	}
+	station := analyzer.station(source)
+	station.coverage.Messages++
+
+	header := record.Header()
+	intervalStart := time.UnixMilli(record.Timestamp).UTC().Truncate(analyzer.interval).UnixMilli()
+	messageTypes, ok := station.intervalMap[intervalStart]
+	if !ok {
+		messageTypes = make(map[uint8]*CoverageMessageType)
+		station.intervalMap[intervalStart] = messageTypes
+		station.intervalVessel[intervalStart] = make(map[uint8]map[uint32]bool)
+	}
+	messageType, ok := messageTypes[header.MessageID]
+	if !ok {
+		messageType = &CoverageMessageType{
+			Type: header.MessageID,
+		}
+		messageTypes[header.MessageID] = messageType
+		station.intervalVessel[intervalStart][header.MessageID] = make(map[uint32]bool)
+	}
+	messageType.Messages++
+	station.intervalVessel[intervalStart][header.MessageID][header.UserID] = true
+
+	latitude, longitude, ok := format.AISPosition(record.AIS.Packet)
+	if !ok {
+		return
+	}
+	station.positions = append(station.positions, coveragePosition{
+		timestamp: record.Timestamp,
+		mmsi:      header.UserID,
+		point: geo.Point{
+			Longitude: longitude,
+			Latitude:  latitude,
+		},
+	})
+}
+
+// locate measures the positions received by each station from the position of its receiver.
+func (analyzer *coverageAnalyzer) locate() {
+	for source, station := range analyzer.stationMap {
+		for _, position := range station.positions {
+			analyzer.locatePosition(source, station, position)
+		}
+		station.positions = nil
+	}
+}
+
+func (analyzer *coverageAnalyzer) locatePosition(source string, station *coverageStation, position coveragePosition) {
+	receiver, ok := analyzer.receiver(source, position.timestamp)
+	if !ok {
+		station.coverage.Unlocated++
+		return
+	}
+	p := position.point
+	distance := geo.Distance(receiver, p) / geo.MetresPerNauticalMile
+	sector := int(geo.Bearing(receiver, p)/360*float64(analyzer.sectors)) % analyzer.sectors
+	station.distances[sector] = append(station.distances[sector], distance)
+	station.coverage.Positions++
+	station.receiverSum.Latitude += receiver.Latitude
+	station.receiverSum.Longitude += receiver.Longitude
+
+	band := int(distance / analyzer.bandWidth)
+	coverageBand, ok := station.bandMap[band]
+	if !ok {
+		coverageBand = &CoverageBand{
+			From: float64(band) * analyzer.bandWidth,
+			To:   float64(band+1) * analyzer.bandWidth,
+		}
+		station.bandMap[band] = coverageBand
+		station.bandVessels[band] = make(map[uint32]bool)
+	}
+	coverageBand.Positions++
+	station.bandVessels[band][position.mmsi] = true
+}
+
+// coverages returns the coverage of each station, ordered by source.
+func (analyzer *coverageAnalyzer) coverages() []*Coverage {
+	var coverages []*Coverage
+	for _, station := range analyzer.stationMap {
+		coverage := station.coverage
+		if coverage.Positions > 0 {
+			coverage.Receiver = geo.Point{
+				Longitude: station.receiverSum.Longitude / float64(coverage.Positions),
+				Latitude:  station.receiverSum.Latitude / float64(coverage.Positions),
+			}
+		}
+
+		coverage.Sectors = []*CoverageSector{}
+		sectorWidth := 360 / float64(analyzer.sectors)
+		for i, distances := range station.distances {
+			slices.Sort(distances)
+			sector := &CoverageSector{
+				From:      float64(i) * sectorWidth,
+				To:        float64(i+1) * sectorWidth,
+				Positions: len(distances),
+				Ranges:    []float64{},
+			}
+			if len(distances) > 0 {
+				sector.MaxRange = distances[len(distances)-1]
+			}
+			for _, percentile := range analyzer.percentiles {
+				sector.Ranges = append(sector.Ranges, percentileOf(distances, percentile))
+			}
+			coverage.Sectors = append(coverage.Sectors, sector)
+		}
+
+		coverage.Bands = []*CoverageBand{}
+		for band, coverageBand := range station.bandMap {
+			coverageBand.Vessels = len(station.bandVessels[band])
+			coverage.Bands = append(coverage.Bands, coverageBand)
+		}
+		sort.Slice(coverage.Bands, func(i, j int) bool {
+			return coverage.Bands[i].From < coverage.Bands[j].From
+		})
+
+		coverage.Intervals = []*CoverageInterval{}
+		for intervalStart, messageTypes := range station.intervalMap {
+			interval := &CoverageInterval{
+				Start: time.UnixMilli(intervalStart).UTC(),
+			}
+			for messageID, messageType := range messageTypes {
+				messageType.Vessels = len(station.intervalVessel[intervalStart][messageID])
+				interval.MessageTypes = append(interval.MessageTypes, *messageType)
+			}
+			sort.Slice(interval.MessageTypes, func(i, j int) bool {
+				return interval.MessageTypes[i].Type < interval.MessageTypes[j].Type
+			})
+			coverage.Intervals = append(coverage.Intervals, interval)
+		}
+		sort.Slice(coverage.Intervals, func(i, j int) bool {
+			return coverage.Intervals[i].Start.Before(coverage.Intervals[j].Start)
+		})
+
+		coverages = append(coverages, coverage)
+	}
+	sort.Slice(coverages, func(i, j int) bool {
+		return coverages[i].Source < coverages[j].Source
+	})
+	return coverages
+}
+
+// percentileOf returns the percentile (0 to 100) of sorted values, using the nearest-rank method. It returns 0 if
+// there are no values.
+func percentileOf(sorted []float64, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func doAisCoverage(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputFormat := cmd.String(coverageFormatFlag.Name)
+	table := cmd.String(coverageTableFlag.Name)
+
+	receivers, err := parseCoverageReceivers(cmd.StringSlice(receiverFlag.Name))
+	if err != nil {
+		return err
+	}
+	analyzer := &coverageAnalyzer{
+		receivers:   receivers,
+		sectors:     cmd.Int(sectorsFlag.Name),
+		bandWidth:   cmd.Float64(bandWidthFlag.Name),
+		interval:    cmd.Duration(coverageIntervalFlag.Name),
+		percentiles: cmd.Float64Slice(percentilesFlag.Name),
+		stationMap:  make(map[string]*coverageStation),
+	}
+	if analyzer.sectors <= 0 {
+		return fmt.Errorf("%s must be positive", sectorsFlag.Name)
+	}
+	if analyzer.bandWidth <= 0 {
+		return fmt.Errorf("%s must be positive", bandWidthFlag.Name)
+	}
+	if analyzer.interval <= 0 {
+		return fmt.Errorf("%s must be positive", coverageIntervalFlag.Name)
+	}
+	for _, percentile := range analyzer.percentiles {
+		if (percentile < 0) || (percentile > 100) {
+			return fmt.Errorf("invalid percentile: %g", percentile)
+		}
+	}
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	// Unless all receiver positions are given, the GNSS fixes are read from a copy of the input, in the same pass as the
+	// AIS messages, so that the input can be read from stdin
+	var input io.Reader = reader
+	var fixesWriter *io.PipeWriter
+	fixesDone := make(chan error, 1)
+	_, ok := receivers["*"]
+	if !ok {
+		var fixesReader *io.PipeReader
+		fixesReader, fixesWriter = io.Pipe()
+		input = io.TeeReader(reader, fixesWriter)
+		go func() {
+			fixMap, err := readCoverageFixes(fixesReader)
+			analyzer.fixMap = fixMap
+			// Fail the AIS pass rather than block it if reading the fixes fails
+			_ = fixesReader.CloseWithError(err)
+			fixesDone <- err
+		}()
+	}
+
+	err = readAISRecordsFrom(input, aisRecordOptions{}, func(aisRecord *format.AISRecord) error {
+		analyzer.add(aisRecord)
+		return nil
+	})
+	if fixesWriter != nil {
+		_ = fixesWriter.CloseWithError(err)
+		fixesErr := <-fixesDone
+		if err == nil {
+			err = fixesErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	analyzer.locate()
+
+	coverages := analyzer.coverages()
+	located := false
+	for _, coverage := range coverages {
+		if (coverage.Positions > 0) || (coverage.Unlocated == 0) {
+			located = true
+		}
+	}
+	if !located {
+		return fmt.Errorf("receiver position unknown: no GNSS fixes near the AIS positions, use --%s", receiverFlag.Name)
+	}
+	switch {
+	case outputFormat == "geojson":
+		return writeCoverageGeoJson(os.Stdout, coverages, analyzer.percentiles)
+	case table == "bands":
+		return writeCoverageBandsCsv(os.Stdout, coverages)
+	case table == "types":
+		return writeCoverageTypesCsv(os.Stdout, coverages)
+	default:
+		return writeCoverageSectorsCsv(os.Stdout, coverages, analyzer.percentiles)
+	}
+}
+
+// writeCoverageGeoJson writes, for each station, a Point feature at the receiver and one Polygon feature for the
+// maximum range and for each percentile range. Each sector of a polygon is an arc at the sector's range.
+func writeCoverageGeoJson(w io.Writer, coverages []*Coverage, percentiles []float64) error {
+	features := []any{}
+	for _, coverage := range coverages {
+		if coverage.Positions == 0 {
+			continue
+		}
+		features = append(features, map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type":        "Point",
+				"coordinates": coverageCoordinates(coverage.Receiver),
+			},
+			"properties": map[string]any{
+				"source":    coverage.Source,
+				"messages":  coverage.Messages,
+				"positions": coverage.Positions,
+				"unlocated": coverage.Unlocated,
+			},
+		})
+		statistics := []string{"max"}
+		for _, percentile := range percentiles {
+			statistics = append(statistics, fmt.Sprintf("p%g", percentile))
+		}
+		for i, statistic := range statistics {
+			var ring [][]float64
+			for _, sector := range coverage.Sectors {
+				r := sector.MaxRange
+				if i > 0 {
+					r = sector.Ranges[i-1]
+				}
+				// One vertex every 2 degrees or less
+				steps := max(int(math.Ceil((sector.To-sector.From)/2)), 1)
+				for step := 0; step <= steps; step++ {
+					bearing := sector.From + (sector.To-sector.From)*float64(step)/float64(steps)
+					p := geo.Destination(coverage.Receiver, bearing, r*geo.MetresPerNauticalMile)
+					ring = append(ring, coverageCoordinates(p))
+				}
+			}
+			ring = append(ring, ring[0])
+			features = append(features, map[string]any{
+				"type": "Feature",
+				"geometry": map[string]any{
+					"type":        "Polygon",
+					"coordinates": [][][]float64{ring},
+				},
+				"properties": map[string]any{
+					"source":    coverage.Source,
+					"statistic": statistic,
+				},
+			})
+		}
+	}
+	return json.NewEncoder(w).Encode(map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+}
+
+func coverageCoordinates(p geo.Point) []float64 {
+	return []float64{
+		math.Round(p.Longitude*1e6) / 1e6,
+		math.Round(p.Latitude*1e6) / 1e6,
+	}
+}
+
+func writeCoverageSectorsCsv(w io.Writer, coverages []*Coverage, percentiles []float64) error {
+	csvWriter := csv.NewWriter(w)
+	headers := []string{"SOURCE", "FROM (DEG)", "TO (DEG)", "POSITIONS", "MAX RANGE (NM)"}
+	for _, percentile := range percentiles {
+		headers = append(headers, fmt.Sprintf("P%g RANGE (NM)", percentile))
+	}
+	err := csvWriter.Write(headers)
+	if err != nil {
+		return err
+	}
+	for _, coverage := range coverages {
+		for _, sector := range coverage.Sectors {
+			cells := []string{
+				coverage.Source,
+				strconv.FormatFloat(sector.From, 'f', -1, 64),
+				strconv.FormatFloat(sector.To, 'f', -1, 64),
+				strconv.Itoa(sector.Positions),
+				formatDistance(sector.MaxRange),
+			}
+			for _, r := range sector.Ranges {
+				cells = append(cells, formatDistance(r))
+			}
+			err = csvWriter.Write(cells)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeCoverageBandsCsv(w io.Writer, coverages []*Coverage) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"SOURCE", "FROM (NM)", "TO (NM)", "POSITIONS", "VESSELS"})
+	if err != nil {
+		return err
+	}
+	for _, coverage := range coverages {
+		for _, band := range coverage.Bands {
+			err = csvWriter.Write([]string{
+				coverage.Source,
+				strconv.FormatFloat(band.From, 'f', -1, 64),
+				strconv.FormatFloat(band.To, 'f', -1, 64),
+				strconv.Itoa(band.Positions),
+				strconv.Itoa(band.Vessels),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeCoverageTypesCsv(w io.Writer, coverages []*Coverage) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"SOURCE", "START", "TYPE", "MESSAGES", "VESSELS"})
+	if err != nil {
+		return err
+	}
+	for _, coverage := range coverages {
+		for _, interval := range coverage.Intervals {
+			for _, messageType := range interval.MessageTypes {
+				err = csvWriter.Write([]string{
+					coverage.Source,
+					interval.Start.Format(time.RFC3339Nano),
+					strconv.Itoa(int(messageType.Type)),
+					strconv.Itoa(messageType.Messages),
+					strconv.Itoa(messageType.Vessels),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
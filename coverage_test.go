@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ngyewch/nmea-logger/geo"
+)
+
+func TestPercentileOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		sorted     []float64
+		percentile float64
+		want       float64
+	}{
+		{"no values", nil, 50, 0},
+		{"one value", []float64{7}, 50, 7},
+		{"zeroth percentile", []float64{1, 2, 3, 4}, 0, 1},
+		{"median of even count", []float64{1, 2, 3, 4}, 50, 2},
+		{"median of odd count", []float64{1, 2, 3, 4, 5}, 50, 3},
+		{"nearest rank rounds up", []float64{1, 2, 3, 4, 5}, 90, 5},
+		{"exact rank", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 90, 9},
+		{"maximum", []float64{1, 2, 3}, 100, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := percentileOf(test.sorted, test.percentile)
+			if got != test.want {
+				t.Errorf("percentileOf(%v, %g) = %g, want %g", test.sorted, test.percentile, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCoverageAnalyzerReceiver(t *testing.T) {
+	analyzer := &coverageAnalyzer{
+		receivers: map[string]geo.Point{
+			"fixed": {Longitude: 100, Latitude: 10},
+		},
+		fixMap: map[string][]coverageFix{
+			"a": {
+				{timestamp: 0, point: geo.Point{Longitude: 1}},
+				{timestamp: 60000, point: geo.Point{Longitude: 2}},
+			},
+			"b": {
+				{timestamp: 0, point: geo.Point{Longitude: 3}},
+			},
+			"": {
+				{timestamp: 0, point: geo.Point{Longitude: 4}},
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		source    string
+		timestamp int64
+		want      geo.Point
+		wantOk    bool
+	}{
+		{"given position", "fixed", 0, geo.Point{Longitude: 100, Latitude: 10}, true},
+		{"fix of the source", "b", 20000, geo.Point{Longitude: 3}, true},
+		{"nearest fix", "a", 40000, geo.Point{Longitude: 2}, true},
+		{"fix without a source", "c", 0, geo.Point{Longitude: 4}, true},
+		{"fix too old", "a", 60000 + coverageMaxFixAge.Milliseconds() + 1, geo.Point{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := analyzer.receiver(test.source, test.timestamp)
+			if (got != test.want) || (ok != test.wantOk) {
+				t.Errorf("receiver(%q, %d) = %v, %t, want %v, %t", test.source, test.timestamp, got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestReadCoverageFixes(t *testing.T) {
+	input := `{"timestamp":10000,"nmea":"$GPRMC,000010.00,A,0112.000,N,10348.000,E,0.0,0.0,010124,,,A*55","source":"a"}
+{"timestamp":20000,"nmea":"$GPRMC,000020.00,A,0130.000,N,10400.000,E,0.0,0.0,010124,,,A*5D","source":"b"}
+{"timestamp":30000,"nmea":"$GPRMC,000030.00,V,0130.000,N,10400.000,E,0.0,0.0,010124,,,A*4B","source":"b"}
+{"timestamp":400`
+	fixMap, err := readCoverageFixes(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readCoverageFixes() error = %v", err)
+	}
+	if (len(fixMap) != 2) || (len(fixMap["a"]) != 1) || (len(fixMap["b"]) != 1) {
+		t.Fatalf("readCoverageFixes() = %v, want one fix for each of a and b", fixMap)
+	}
+	if (fixMap["a"][0].timestamp != 10000) || (fixMap["a"][0].point != geo.Point{Longitude: 103.8, Latitude: 1.2}) {
+		t.Errorf("fix of a = %+v", fixMap["a"][0])
+	}
+	if (fixMap["b"][0].timestamp != 20000) || (fixMap["b"][0].point != geo.Point{Longitude: 104, Latitude: 1.5}) {
+		t.Errorf("fix of b = %+v", fixMap["b"][0])
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	knotsToMetresPerSecond = geo.MetresPerNauticalMile / 3600.0
+	// encounterMaxSpeed is the speed, in knots, above which reported speeds are considered bogus when sizing the
+	// search area.
+	encounterMaxSpeed = 50
+)
+
+// Encounter is a close-quarters situation between two vessels: a period during which their predicted closest point of
+// approach (CPA) is within the distance threshold, and the time to it (TCPA) within the time threshold. Time is when the
+// CPA is smallest; the vessel states, Range, CPA and TCPA are as of Time. MinRange is the smallest distance between the
+// vessels during the encounter. Distances are in nautical miles, TCPA is in seconds.
+type Encounter struct {
+	Vessels      [2]EncounterVessel `json:"vessels"`
+	Start        time.Time          `json:"start"`
+	End          time.Time          `json:"end"`
+	Time         time.Time          `json:"time"`
+	Range        float64            `json:"range"`
+	CPA          float64            `json:"cpa"`
+	TCPA         float64            `json:"tcpa"`
+	CPATime      time.Time          `json:"cpaTime"`
+	MinRange     float64            `json:"minRange"`
+	MinRangeTime time.Time          `json:"minRangeTime"`
+}
+
+// EncounterVessel is the interpolated state of a vessel in an encounter. Sog is in knots, Cog in degrees.
+type EncounterVessel struct {
+	MMSI      uint32  `json:"mmsi"`
+	Name      *string `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Sog       float64 `json:"sog"`
+	Cog       float64 `json:"cog"`
+}
+
+// encounterState is the state of a vessel at a time step. x and y are metres east and north in an equirectangular
+// projection, used for spatial bucketing.
+type encounterState struct {
+	mmsi  uint32
+	point geo.Point
+	sog   float64
+	cog   float64
+	x     float64
+	y     float64
+}
+
+type encounterTrack struct {
+	mmsi   uint32
+	points []vesselTrackPoint
+	cursor int
+}
+
+// stateAt interpolates the state of the vessel at t. ok is false if t is outside the track, or if the reports around t
+// are further apart than maxGap. Tracks must be evaluated at increasing times.
+func (track *encounterTrack) stateAt(t int64, maxGap int64) (state encounterState, ok bool) {
+	points := track.points
+	for (track.cursor+1 < len(points)) && (points[track.cursor+1].timestamp <= t) {
+		track.cursor++
+	}
+	a := points[track.cursor]
+	if a.timestamp > t {
+		return state, false
+	}
+	b := a
+	if a.timestamp < t {
+		if track.cursor+1 >= len(points) {
+			return state, false
+		}
+		b = points[track.cursor+1]
+		if b.timestamp-a.timestamp > maxGap {
+			return state, false
+		}
+	}
+
+	state.mmsi = track.mmsi
+	f := 0.0
+	if b.timestamp > a.timestamp {
+		f = float64(t-a.timestamp) / float64(b.timestamp-a.timestamp)
+	}
+	state.point = geo.Point{
+		Longitude: a.point.Longitude + (b.point.Longitude-a.point.Longitude)*f,
+		Latitude:  a.point.Latitude + (b.point.Latitude-a.point.Latitude)*f,
+	}
+
+	// The velocity is taken from the nearest report, or else derived from the positions around t.
+	nearest := a
+	if f > 0.5 {
+		nearest = b
+	}
+	switch {
+	case (nearest.sog != nil) && (nearest.cog != nil):
+		state.sog = *nearest.sog
+		state.cog = *nearest.cog
+	case b.timestamp > a.timestamp:
+		hours := float64(b.timestamp-a.timestamp) / float64(time.Hour.Milliseconds())
+		state.sog = geo.Distance(a.point, b.point) / geo.MetresPerNauticalMile / hours
+		state.cog = geo.Bearing(a.point, b.point)
+	default:
+		return state, false
+	}
+
+	latitude := state.point.Latitude * math.Pi / 180
+	state.x = state.point.Longitude * math.Pi / 180 * math.Cos(latitude) * geo.EarthRadius
+	state.y = latitude * geo.EarthRadius
+	return state, true
+}
+
+// cpa returns the distance at the closest point of approach, in metres, and the time to it, in seconds, assuming both
+// vessels keep their course and speed. tcpa is negative if the vessels are moving apart.
+func cpa(s1 *encounterState, s2 *encounterState) (distance float64, tcpa float64) {
+	latitude := (s1.point.Latitude + s2.point.Latitude) / 2 * math.Pi / 180
+	dx := (s2.point.Longitude - s1.point.Longitude) * math.Pi / 180 * math.Cos(latitude) * geo.EarthRadius
+	dy := (s2.point.Latitude - s1.point.Latitude) * math.Pi / 180 * geo.EarthRadius
+	vx1, vy1 := velocity(s1)
+	vx2, vy2 := velocity(s2)
+	dvx := vx2 - vx1
+	dvy := vy2 - vy1
+	dv2 := dvx*dvx + dvy*dvy
+	if dv2 < 1e-9 {
+		return math.Hypot(dx, dy), 0
+	}
+	tcpa = -(dx*dvx + dy*dvy) / dv2
+	if tcpa == 0 {
+		// Avoid negative zero
+		tcpa = 0
+	}
+	return math.Hypot(dx+dvx*tcpa, dy+dvy*tcpa), tcpa
+}
+
+// velocity returns the velocity east and north, in metres per second.
+func velocity(state *encounterState) (vx float64, vy float64) {
+	v := state.sog * knotsToMetresPerSecond
+	cog := state.cog * math.Pi / 180
+	return v * math.Sin(cog), v * math.Cos(cog)
+}
+
+type encounterDetector struct {
+	cpaThreshold    float64
+	tcpaThreshold   time.Duration
+	step            time.Duration
+	maxGap          time.Duration
+	stationarySpeed float64
+	trackMap        map[uint32][]vesselTrackPoint
+	registry        *vesselRegistry
+
+	activeMap  map[[2]uint32]*Encounter
+	lastStep   map[[2]uint32]int64
+	encounters []*Encounter
+}
+
+func (detector *encounterDetector) add(record *format.AISRecord) {
+	detector.registry.add(record)
+	trackPoint, ok := vesselTrackPointOf(record)
+	if !ok {
+		return
+	}
+	mmsi := record.AIS.Packet.GetHeader().UserID
+	detector.trackMap[mmsi] = append(detector.trackMap[mmsi], trackPoint)
+}
+
+// detect evaluates all vessel pairs at each time step. Vessels are bucketed in a grid whose cells are as large as the
+// distance two vessels can close in the TCPA threshold, so that only vessels in neighbouring cells are paired.
+func (detector *encounterDetector) detect() []*Encounter {
+	detector.activeMap = make(map[[2]uint32]*Encounter)
+	detector.lastStep = make(map[[2]uint32]int64)
+
+	var tracks []*encounterTrack
+	var first, last int64
+	for _, mmsi := range sortedMapKeys(detector.trackMap) {
+		points := detector.trackMap[mmsi]
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].timestamp < points[j].timestamp
+		})
+		if (len(tracks) == 0) || (points[0].timestamp < first) {
+			first = points[0].timestamp
+		}
+		if (len(tracks) == 0) || (points[len(points)-1].timestamp > last) {
+			last = points[len(points)-1].timestamp
+		}
+		tracks = append(tracks, &encounterTrack{
+			mmsi:   mmsi,
+			points: points,
+		})
+	}
+
+	step := detector.step.Milliseconds()
+	maxGap := detector.maxGap.Milliseconds()
+	cpaThreshold := detector.cpaThreshold * geo.MetresPerNauticalMile
+	for t := first - first%step; (len(tracks) > 0) && (t <= last); t += step {
+		var states []encounterState
+		maxSog := 0.0
+		for _, track := range tracks {
+			state, ok := track.stateAt(t, maxGap)
+			if ok {
+				states = append(states, state)
+				maxSog = max(maxSog, min(state.sog, encounterMaxSpeed))
+			}
+		}
+		cellSize := cpaThreshold + 2*maxSog*knotsToMetresPerSecond*detector.tcpaThreshold.Seconds()
+		cellSize = max(cellSize, 1)
+		grid := make(map[[2]int64][]int)
+		for i := range states {
+			cell := [2]int64{int64(math.Floor(states[i].x / cellSize)), int64(math.Floor(states[i].y / cellSize))}
+			grid[cell] = append(grid[cell], i)
+		}
+		for cell, indices := range grid {
+			for dx := int64(-1); dx <= 1; dx++ {
+				for dy := int64(-1); dy <= 1; dy++ {
+					for _, j := range grid[[2]int64{cell[0] + dx, cell[1] + dy}] {
+						for _, i := range indices {
+							if states[i].mmsi < states[j].mmsi {
+								detector.evaluate(t, &states[i], &states[j], cpaThreshold)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, encounter := range detector.activeMap {
+		detector.encounters = append(detector.encounters, encounter)
+	}
+	sort.Slice(detector.encounters, func(i, j int) bool {
+		a, b := detector.encounters[i], detector.encounters[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Vessels[0].MMSI != b.Vessels[0].MMSI {
+			return a.Vessels[0].MMSI < b.Vessels[0].MMSI
+		}
+		return a.Vessels[1].MMSI < b.Vessels[1].MMSI
+	})
+	return detector.encounters
+}
+
+// evaluate checks a vessel pair at time step t, and starts or extends their encounter if it is a close-quarters
+// situation.
+func (detector *encounterDetector) evaluate(t int64, s1 *encounterState, s2 *encounterState, cpaThreshold float64) {
+	if (s1.sog < detector.stationarySpeed) && (s2.sog < detector.stationarySpeed) {
+		return
+	}
+	distance, tcpa := cpa(s1, s2)
+	if (distance > cpaThreshold) || (tcpa < 0) || (tcpa > detector.tcpaThreshold.Seconds()) {
+		return
+	}
+
+	key := [2]uint32{s1.mmsi, s2.mmsi}
+	tm := time.UnixMilli(t).UTC()
+	encounter, ok := detector.activeMap[key]
+	if ok && (detector.lastStep[key] < t-detector.step.Milliseconds()) {
+		detector.encounters = append(detector.encounters, encounter)
+		ok = false
+	}
+	detector.lastStep[key] = t
+	r := geo.Distance(s1.point, s2.point) / geo.MetresPerNauticalMile
+	if ok {
+		encounter.End = tm
+		if r < encounter.MinRange {
+			encounter.MinRange = r
+			encounter.MinRangeTime = tm
+		}
+		if distance/geo.MetresPerNauticalMile >= encounter.CPA {
+			return
+		}
+	} else {
+		encounter = &Encounter{
+			Start:        tm,
+			End:          tm,
+			MinRange:     r,
+			MinRangeTime: tm,
+		}
+		detector.activeMap[key] = encounter
+	}
+	encounter.Vessels = [2]EncounterVessel{detector.encounterVessel(s1), detector.encounterVessel(s2)}
+	encounter.Time = tm
+	encounter.Range = r
+	encounter.CPA = distance / geo.MetresPerNauticalMile
+	encounter.TCPA = tcpa
+	encounter.CPATime = tm.Add(time.Duration(tcpa * float64(time.Second)))
+}
+
+func (detector *encounterDetector) encounterVessel(state *encounterState) EncounterVessel {
+	encounterVessel := EncounterVessel{
+		MMSI:      state.mmsi,
+		Latitude:  state.point.Latitude,
+		Longitude: state.point.Longitude,
+		Sog:       state.sog,
+		Cog:       state.cog,
+	}
+	vessel, ok := detector.registry.vesselMap[state.mmsi]
+	if ok {
+		encounterVessel.Name = vessel.Name
+	}
+	return encounterVessel
+}
+
+func doAisEncounters(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+	outputFormat := cmd.String(jsonCsvFormatFlag.Name)
+
+	detector := &encounterDetector{
+		cpaThreshold:    cmd.Float64(cpaFlag.Name),
+		tcpaThreshold:   cmd.Duration(tcpaFlag.Name),
+		step:            cmd.Duration(stepFlag.Name),
+		maxGap:          cmd.Duration(interpolationGapFlag.Name),
+		stationarySpeed: cmd.Float64(stationarySpeedFlag.Name),
+		trackMap:        make(map[uint32][]vesselTrackPoint),
+		registry:        newVesselRegistry(),
+	}
+	if detector.step.Milliseconds() <= 0 {
+		return fmt.Errorf("%s must be positive", stepFlag.Name)
+	}
+
+	err := readAISRecords(inputFiles, aisRecordOptions{MMSIs: mmsis, DedupeWindow: dedupeWindow}, func(aisRecord *format.AISRecord) error {
+		detector.add(aisRecord)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	encounters := detector.detect()
+	switch outputFormat {
+	case "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		if encounters == nil {
+			encounters = []*Encounter{}
+		}
+		return jsonEncoder.Encode(encounters)
+
+	default:
+		return writeEncountersCsv(os.Stdout, encounters)
+	}
+}
+
+func writeEncountersCsv(w io.Writer, encounters []*Encounter) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"START", "END", "TIME", "CPA (NM)", "TCPA (S)", "CPA TIME", "RANGE (NM)", "MIN RANGE (NM)", "MIN RANGE TIME",
+		"MMSI 1", "NAME 1", "LATITUDE 1", "LONGITUDE 1", "SOG 1", "COG 1",
+		"MMSI 2", "NAME 2", "LATITUDE 2", "LONGITUDE 2", "SOG 2", "COG 2",
+	})
+	if err != nil {
+		return err
+	}
+	for _, encounter := range encounters {
+		cells := []string{
+			encounter.Start.Format(time.RFC3339Nano),
+			encounter.End.Format(time.RFC3339Nano),
+			encounter.Time.Format(time.RFC3339Nano),
+			fmt.Sprintf("%.3f", encounter.CPA),
+			fmt.Sprintf("%.0f", encounter.TCPA),
+			encounter.CPATime.Format(time.RFC3339Nano),
+			fmt.Sprintf("%.3f", encounter.Range),
+			fmt.Sprintf("%.3f", encounter.MinRange),
+			encounter.MinRangeTime.Format(time.RFC3339Nano),
+		}
+		for _, vessel := range encounter.Vessels {
+			cells = append(cells,
+				strconv.FormatInt(int64(vessel.MMSI), 10),
+				formatOptional(vessel.Name),
+				fmt.Sprintf("%.5f", vessel.Latitude),
+				fmt.Sprintf("%.5f", vessel.Longitude),
+				fmt.Sprintf("%.1f", vessel.Sog),
+				fmt.Sprintf("%.1f", vessel.Cog),
+			)
+		}
+		err = csvWriter.Write(cells)
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ngyewch/nmea-logger/geo"
+)
+
+func TestCpa(t *testing.T) {
+	// d is the length of 0.1 degrees on the equator, in metres, and v is 10 knots, in metres per second.
+	d := 0.1 * math.Pi / 180 * geo.EarthRadius
+	v := 10 * knotsToMetresPerSecond
+	tests := []struct {
+		name         string
+		s1, s2       encounterState
+		wantDistance float64
+		wantTcpa     float64
+	}{
+		{
+			"stationary",
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0}},
+			encounterState{point: geo.Point{Longitude: 0.1, Latitude: 0}},
+			d, 0,
+		},
+		{
+			"same velocity",
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0}, sog: 10, cog: 45},
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0.1}, sog: 10, cog: 45},
+			d, 0,
+		},
+		{
+			"head-on",
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0}, sog: 10, cog: 90},
+			encounterState{point: geo.Point{Longitude: 0.1, Latitude: 0}, sog: 10, cog: 270},
+			0, d / (2 * v),
+		},
+		{
+			"crossing",
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0}, sog: 10, cog: 0},
+			encounterState{point: geo.Point{Longitude: 0.1, Latitude: 0}, sog: 10, cog: 270},
+			d / math.Sqrt2, d / (2 * v),
+		},
+		{
+			"moving apart",
+			encounterState{point: geo.Point{Longitude: 0, Latitude: 0}},
+			encounterState{point: geo.Point{Longitude: 0.1, Latitude: 0}, sog: 10, cog: 90},
+			0, -d / v,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distance, tcpa := cpa(&test.s1, &test.s2)
+			if math.Abs(distance-test.wantDistance) > 1e-6 {
+				t.Errorf("cpa() distance = %v, want %v", distance, test.wantDistance)
+			}
+			if math.Abs(tcpa-test.wantTcpa) > 1e-6 {
+				t.Errorf("cpa() tcpa = %v, want %v", tcpa, test.wantTcpa)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+type LogFilter struct {
+	From             time.Time
+	To               time.Time
+	SentencePatterns []string
+	TalkerPatterns   []string
+	AISMessageTypes  map[uint8]bool
+	MMSIs            map[uint32]bool
+	BoundingBox      *geo.BoundingBox
+	Polygons         []*geo.Polygon
+}
+
+func newLogFilter(cmd *cli.Command) (*LogFilter, error) {
+	filter := &LogFilter{
+		From:             cmd.Timestamp(fromFlag.Name),
+		To:               cmd.Timestamp(toFlag.Name),
+		SentencePatterns: cmd.StringSlice(sentenceFlag.Name),
+		TalkerPatterns:   cmd.StringSlice(talkerFlag.Name),
+	}
+	for _, pattern := range append(filter.SentencePatterns, filter.TalkerPatterns...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	aisMessageTypes := cmd.IntSlice(aisTypeFlag.Name)
+	if len(aisMessageTypes) > 0 {
+		filter.AISMessageTypes = make(map[uint8]bool)
+		for _, aisMessageType := range aisMessageTypes {
+			filter.AISMessageTypes[uint8(aisMessageType)] = true
+		}
+	}
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	if len(mmsis) > 0 {
+		filter.MMSIs = make(map[uint32]bool)
+		for _, mmsi := range mmsis {
+			filter.MMSIs[mmsi] = true
+		}
+	}
+	bbox := cmd.Float64Slice(bboxFlag.Name)
+	if len(bbox) > 0 {
+		if len(bbox) != 4 {
+			return nil, fmt.Errorf("bounding box must be min-lon,min-lat,max-lon,max-lat")
+		}
+		filter.BoundingBox = &geo.BoundingBox{
+			MinLongitude: bbox[0],
+			MinLatitude:  bbox[1],
+			MaxLongitude: bbox[2],
+			MaxLatitude:  bbox[3],
+		}
+	}
+	polygonFile := cmd.String(polygonFlag.Name)
+	if polygonFile != "" {
+		polygons, err := geo.LoadPolygons(polygonFile)
+		if err != nil {
+			return nil, err
+		}
+		filter.Polygons = polygons
+	}
+	return filter, nil
+}
+
+func (filter *LogFilter) hasAISFilters() bool {
+	return (filter.AISMessageTypes != nil) || (filter.MMSIs != nil) || (filter.BoundingBox != nil) ||
+		(len(filter.Polygons) > 0)
+}
+
+func (filter *LogFilter) hasSentenceFilters() bool {
+	return (len(filter.SentencePatterns) > 0) || (len(filter.TalkerPatterns) > 0)
+}
+
+func (filter *LogFilter) isEmpty() bool {
+	return filter.From.IsZero() && filter.To.IsZero() && !filter.hasSentenceFilters() && !filter.hasAISFilters()
+}
+
+func (filter *LogFilter) matchesTimestamp(timestamp int64) bool {
+	if !filter.From.IsZero() && (timestamp < filter.From.UnixMilli()) {
+		return false
+	}
+	if !filter.To.IsZero() && (timestamp >= filter.To.UnixMilli()) {
+		return false
+	}
+	return true
+}
+
+func (filter *LogFilter) matchesSentence(sentenceInfo *format.SentenceInfo) bool {
+	return matchesAnyPattern(filter.SentencePatterns, sentenceInfo.Type) &&
+		matchesAnyPattern(filter.TalkerPatterns, sentenceInfo.Talker)
+}
+
+// matchesAISPacket checks the AIS filters. Packets that do not carry a position pass the area filters.
+func (filter *LogFilter) matchesAISPacket(packet ais.Packet) bool {
+	if packet == nil {
+		return false
+	}
+	header := packet.GetHeader()
+	if (filter.AISMessageTypes != nil) && !filter.AISMessageTypes[header.MessageID] {
+		return false
+	}
+	if (filter.MMSIs != nil) && !filter.MMSIs[header.UserID] {
+		return false
+	}
+	if (filter.BoundingBox == nil) && (len(filter.Polygons) == 0) {
+		return true
+	}
+	latitude, longitude, ok := format.AISPosition(packet)
+	if !ok {
+		return true
+	}
+	p := geo.Point{
+		Longitude: longitude,
+		Latitude:  latitude,
+	}
+	if (filter.BoundingBox != nil) && !filter.BoundingBox.Contains(p) {
+		return false
+	}
+	if len(filter.Polygons) > 0 {
+		for _, polygon := range filter.Polygons {
+			if polygon.Contains(p) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesAnyPattern(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		matched, _ := path.Match(pattern, s)
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func doFilter(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputFile := cmd.String(outputFlag.Name)
+	dropInvalid := cmd.Bool(dropInvalidFlag.Name)
+
+	filter, err := newLogFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+	if err != nil {
+		return err
+	}
+	defer func(f io.WriteCloser) {
+		_ = f.Close()
+	}(f)
+	if filepath.Ext(outputFile1) != ".jsonl" {
+		return fmt.Errorf("unsupported file extension")
+	}
+	loggerRecordWriter := format.NewLoggerRecordWriter(f)
+	defer func(loggerRecordWriter *format.LoggerRecordWriter) {
+		_ = loggerRecordWriter.Close()
+	}(loggerRecordWriter)
+
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	aisSentenceGrouper := format.NewAISSentenceGrouper()
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				if dropInvalid || !filter.isEmpty() {
+					continue
+				}
+				err = loggerRecordWriter.WriteLine(malformedRecordError.Text)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if loggerRecord == nil {
+			break
+		}
+
+		if !filter.matchesTimestamp(loggerRecord.Timestamp) {
+			continue
+		}
+		// Invalid sentences are kept unless dropped explicitly, or subject to a filter that cannot be checked.
+		sentenceInfo, err := format.ParseSentenceInfo(loggerRecord.NMEA)
+		if err != nil {
+			if dropInvalid || filter.hasSentenceFilters() || filter.hasAISFilters() {
+				continue
+			}
+			err = loggerRecordWriter.WriteLoggerRecord(loggerRecord)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !filter.matchesSentence(sentenceInfo) {
+			continue
+		}
+		if !sentenceInfo.ChecksumValid {
+			if dropInvalid || (sentenceInfo.Encapsulated && filter.hasAISFilters()) {
+				continue
+			}
+			err = loggerRecordWriter.WriteLoggerRecord(loggerRecord)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !sentenceInfo.Encapsulated || !filter.hasAISFilters() {
+			err = loggerRecordWriter.WriteLoggerRecord(loggerRecord)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		aisSentenceGroup, err := aisSentenceGrouper.Add(loggerRecord, sentenceInfo)
+		if err != nil || (aisSentenceGroup == nil) {
+			continue
+		}
+		if !filter.matchesAISPacket(aisSentenceGroup.VDM.Packet) {
+			continue
+		}
+		for _, record := range aisSentenceGroup.Records {
+			err = loggerRecordWriter.WriteLoggerRecord(record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
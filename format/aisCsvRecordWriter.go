@@ -2,114 +2,167 @@ package format
 
 import (
 	"encoding/csv"
-	"fmt"
 	"io"
 	"math"
-	"strconv"
-	"time"
 
 	"github.com/BertoldVdb/go-ais"
 )
 
+// csvDefaultColumns are the columns of CsvAISRecordWriter if none are configured.
+var csvDefaultColumns = []string{
+	"datetime", "epoch", "mmsi",
+	"latitude", "longitude", "course", "speed", "heading", "navstat",
+	"imo", "name", "callsign", "aistype", "a", "b", "c", "d", "draught", "destination", "eta",
+}
+
+// CsvAISRecordWriter writes one row per position report, joined with the vessel's static data. Rows of vessels whose
+// static data has not been received in full yet are held back for up to CsvOptions.StaticDataLookahead, so that static
+// data received shortly after a position report can be backfilled. Rows are written in input order.
 type CsvAISRecordWriter struct {
-	csvWriter         *csv.Writer
-	shipStaticDataMap map[uint32]*ais.ShipStaticData
+	options       CsvOptions
+	columns       []csvColumn
+	csvWriter     *csv.Writer
+	staticDataMap map[uint32]*aisStaticData
+	pendingRows   []*csvRow
 }
 
-func NewCsvAISRecordWriter(w io.Writer) (*CsvAISRecordWriter, error) {
-	csvWriter := csv.NewWriter(w)
-	err := csvWriter.Write([]string{
-		"DATE TIME (UTC)",
-		"EPOCH TIME",
-		"MMSI",
-		"LATITUDE",
-		"LONGITUDE",
-		"COURSE",
-		"SPEED",
-		"HEADING",
-		"NAVSTAT",
-		"IMO",
-		"NAME",
-		"CALLSIGN",
-		"AISTYPE",
-		"A",
-		"B",
-		"C",
-		"D",
-		"DRAUGHT",
-		"DESTINATION",
-		"ETA",
-	})
+func NewCsvAISRecordWriter(w io.Writer, options CsvOptions) (*CsvAISRecordWriter, error) {
+	columns, err := options.columnsOf(options.Columns, csvDefaultColumns)
+	if err != nil {
+		return nil, err
+	}
+	csvWriter := options.newWriter(w)
+	err = csvWriter.Write(options.header(columns))
 	if err != nil {
 		return nil, err
 	}
 	return &CsvAISRecordWriter{
-		csvWriter:         csvWriter,
-		shipStaticDataMap: make(map[uint32]*ais.ShipStaticData),
+		options:       options,
+		columns:       columns,
+		csvWriter:     csvWriter,
+		staticDataMap: make(map[uint32]*aisStaticData),
 	}, nil
 }
 
 func (writer *CsvAISRecordWriter) Close() error {
+	err := writer.writePendingRows(math.MaxInt64)
+	if err != nil {
+		return err
+	}
 	writer.csvWriter.Flush()
-	return nil
+	return writer.csvWriter.Error()
 }
 
-func (writer *CsvAISRecordWriter) PreprocessRecord(record *AISRecord) error {
-	switch report := record.AIS.Packet.(type) {
-	case ais.ShipStaticData:
-		_, ok := writer.shipStaticDataMap[report.UserID]
-		if !ok {
-			writer.shipStaticDataMap[report.UserID] = &report
+func (writer *CsvAISRecordWriter) WriteAISRecord(record *AISRecord) error {
+	staticData, ok := writer.updateStaticData(record.AIS.Packet)
+	if ok {
+		for _, row := range writer.pendingRows {
+			if (row.UserID == staticData.UserID) && !row.StaticData.complete() {
+				row.StaticData = staticData
+			}
 		}
 	}
-	return nil
+
+	position, ok := aisVesselPositionOf(record.AIS.Packet)
+	if ok {
+		writer.pendingRows = append(writer.pendingRows, &csvRow{
+			Timestamp:  record.Timestamp,
+			UserID:     position.UserID,
+			Position:   position,
+			StaticData: writer.staticDataMap[position.UserID],
+		})
+	}
+
+	return writer.writePendingRows(record.Timestamp - writer.options.StaticDataLookahead.Milliseconds())
 }
 
-func (writer *CsvAISRecordWriter) WriteAISRecord(record *AISRecord) error {
-	const dateTimeLayout = "2006-01-02 15:04:05"
-	switch report := record.AIS.Packet.(type) {
-	case ais.PositionReport:
-		t := time.UnixMilli(record.Timestamp)
-		var cells []string
-		cells = append(cells,
-			t.Format(dateTimeLayout),
-			strconv.FormatInt(record.Timestamp/1000, 10),
-			strconv.FormatInt(int64(report.UserID), 10),
-			strconv.FormatFloat(roundToDecimalPoints(float64(report.Latitude), 5), 'f', -1, 64),
-			strconv.FormatFloat(roundToDecimalPoints(float64(report.Longitude), 5), 'f', -1, 64),
-			strconv.FormatFloat(float64(report.Cog), 'f', -1, 64),
-			strconv.FormatFloat(float64(report.Sog), 'f', -1, 64),
-			strconv.FormatInt(int64(report.TrueHeading), 10),
-			strconv.FormatInt(int64(report.NavigationalStatus), 10),
-		)
-		shipStaticData := writer.shipStaticDataMap[report.UserID]
-		if shipStaticData != nil {
-			cells = append(cells,
-				strconv.FormatInt(int64(shipStaticData.ImoNumber), 10),
-				shipStaticData.Name,
-				shipStaticData.CallSign,
-				strconv.FormatInt(int64(shipStaticData.Type), 10),
-				strconv.FormatInt(int64(shipStaticData.Dimension.A), 10),
-				strconv.FormatInt(int64(shipStaticData.Dimension.B), 10),
-				strconv.FormatInt(int64(shipStaticData.Dimension.C), 10),
-				strconv.FormatInt(int64(shipStaticData.Dimension.D), 10),
-				strconv.FormatFloat(float64(shipStaticData.MaximumStaticDraught), 'f', -1, 64),
-				shipStaticData.Destination,
-				fmt.Sprintf("%02d-%02d %02d:%02d", shipStaticData.Eta.Month, shipStaticData.Eta.Day, shipStaticData.Eta.Hour, shipStaticData.Eta.Minute),
-			)
+// writePendingRows writes pending rows, in order, until a row is reached that has incomplete static data and is not
+// older than the deadline.
+func (writer *CsvAISRecordWriter) writePendingRows(deadline int64) error {
+	n := 0
+	for _, row := range writer.pendingRows {
+		if !row.StaticData.complete() && (row.Timestamp > deadline) {
+			break
 		}
-
-		err := writer.csvWriter.Write(cells)
+		err := writer.csvWriter.Write(writer.options.row(writer.columns, row))
 		if err != nil {
 			return err
 		}
-
-	case ais.ShipStaticData:
-		writer.shipStaticDataMap[report.UserID] = &report
+		n++
 	}
+	writer.pendingRows = writer.pendingRows[n:]
 	return nil
 }
 
+// updateStaticData merges the static data carried by packet, if any, and returns the vessel's updated static data.
+// Static data is copied on update, as pending rows refer to the static data that was current when they were queued.
+func (writer *CsvAISRecordWriter) updateStaticData(packet ais.Packet) (*aisStaticData, bool) {
+	userID := packet.GetHeader().UserID
+	staticData := &aisStaticData{
+		UserID: userID,
+	}
+	current, ok := writer.staticDataMap[userID]
+	if ok {
+		*staticData = *current
+	}
+	if !staticData.update(packet) {
+		return nil, false
+	}
+	writer.staticDataMap[userID] = staticData
+	return staticData, true
+}
+
+// aisVesselPosition is a position report from a Class A (types 1-3) or Class B (types 18 and 19) vessel.
+type aisVesselPosition struct {
+	UserID             uint32
+	Class              string
+	Latitude           float64
+	Longitude          float64
+	Cog                float64
+	Sog                float64
+	TrueHeading        uint16
+	NavigationalStatus *uint8
+}
+
+func aisVesselPositionOf(packet ais.Packet) (*aisVesselPosition, bool) {
+	switch report := packet.(type) {
+	case ais.PositionReport:
+		return &aisVesselPosition{
+			UserID:             report.UserID,
+			Class:              "A",
+			Latitude:           float64(report.Latitude),
+			Longitude:          float64(report.Longitude),
+			Cog:                float64(report.Cog),
+			Sog:                float64(report.Sog),
+			TrueHeading:        report.TrueHeading,
+			NavigationalStatus: &report.NavigationalStatus,
+		}, true
+
+	case ais.StandardClassBPositionReport:
+		return &aisVesselPosition{
+			UserID:      report.UserID,
+			Class:       "B",
+			Latitude:    float64(report.Latitude),
+			Longitude:   float64(report.Longitude),
+			Cog:         float64(report.Cog),
+			Sog:         float64(report.Sog),
+			TrueHeading: report.TrueHeading,
+		}, true
+
+	case ais.ExtendedClassBPositionReport:
+		return &aisVesselPosition{
+			UserID:      report.UserID,
+			Class:       "B",
+			Latitude:    float64(report.Latitude),
+			Longitude:   float64(report.Longitude),
+			Cog:         float64(report.Cog),
+			Sog:         float64(report.Sog),
+			TrueHeading: report.TrueHeading,
+		}, true
+	}
+	return nil, false
+}
+
 func roundToDecimalPoints(v float64, decimalPoints int) float64 {
 	multiplier := math.Pow10(decimalPoints)
 	return math.Round(v*multiplier) / multiplier
@@ -0,0 +1,308 @@
+package format
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/BertoldVdb/go-ais"
+)
+
+// CsvTableCreator creates the named table. A table is complete once the next table is created, or once the creator
+// is closed. *zip.Writer implements CsvTableCreator.
+type CsvTableCreator interface {
+	Create(name string) (io.Writer, error)
+}
+
+const (
+	CsvPositionsTable        = "positions.csv"
+	CsvVesselsTable          = "vessels.csv"
+	CsvBaseStationsTable     = "base_stations.csv"
+	CsvAidsToNavigationTable = "aids_to_navigation.csv"
+)
+
+type csvStationSighting struct {
+	firstSeen int64
+	lastSeen  int64
+	reports   int
+}
+
+func (sighting *csvStationSighting) add(timestamp int64) {
+	if sighting.reports == 0 || timestamp < sighting.firstSeen {
+		sighting.firstSeen = timestamp
+	}
+	if timestamp > sighting.lastSeen {
+		sighting.lastSeen = timestamp
+	}
+	sighting.reports++
+}
+
+func (sighting *csvStationSighting) cells(options *CsvOptions) []string {
+	if sighting.reports == 0 {
+		return []string{"", "", "0"}
+	}
+	return []string{
+		options.formatDateTime(sighting.firstSeen),
+		options.formatDateTime(sighting.lastSeen),
+		strconv.Itoa(sighting.reports),
+	}
+}
+
+func csvSightingHeader(options *CsvOptions) []string {
+	return []string{
+		options.dateTimeHeader("FIRST SEEN"),
+		options.dateTimeHeader("LAST SEEN"),
+		"REPORTS",
+	}
+}
+
+type csvVessel struct {
+	csvStationSighting
+	class      string
+	staticData *aisStaticData
+}
+
+type csvBaseStation struct {
+	csvStationSighting
+	report ais.BaseStationReport
+}
+
+type csvAidToNavigation struct {
+	csvStationSighting
+	report ais.AidsToNavigationReport
+}
+
+// MultiTableCsvAISRecordWriter writes related CSV tables: one row per position report in CsvPositionsTable, and one
+// row per station in CsvVesselsTable, CsvBaseStationsTable and CsvAidsToNavigationTable. Position rows are streamed;
+// the station tables are buffered in memory and written on Close.
+type MultiTableCsvAISRecordWriter struct {
+	options         CsvOptions
+	tableCreator    CsvTableCreator
+	positionColumns []csvColumn
+	positionsWriter *csv.Writer
+	vesselMap       map[uint32]*csvVessel
+	baseStationMap  map[uint32]*csvBaseStation
+	atonMap         map[uint32]*csvAidToNavigation
+}
+
+// csvDefaultPositionColumns are the columns of CsvPositionsTable if none are configured.
+var csvDefaultPositionColumns = []string{
+	"datetime", "epoch", "mmsi", "class",
+	"latitude", "longitude", "course", "speed", "heading", "navstat",
+}
+
+// csvVesselStaticDataColumns are the static data columns of CsvVesselsTable.
+var csvVesselStaticDataColumns = []string{
+	"imo", "name", "callsign", "aistype", "a", "b", "c", "d", "draught", "destination", "eta",
+}
+
+func NewMultiTableCsvAISRecordWriter(tableCreator CsvTableCreator, options CsvOptions) (*MultiTableCsvAISRecordWriter, error) {
+	positionColumns, err := options.columnsOf(options.Columns, csvDefaultPositionColumns)
+	if err != nil {
+		return nil, err
+	}
+	w, err := tableCreator.Create(CsvPositionsTable)
+	if err != nil {
+		return nil, err
+	}
+	positionsWriter := options.newWriter(w)
+	err = positionsWriter.Write(options.header(positionColumns))
+	if err != nil {
+		return nil, err
+	}
+	return &MultiTableCsvAISRecordWriter{
+		options:         options,
+		tableCreator:    tableCreator,
+		positionColumns: positionColumns,
+		positionsWriter: positionsWriter,
+		vesselMap:       make(map[uint32]*csvVessel),
+		baseStationMap:  make(map[uint32]*csvBaseStation),
+		atonMap:         make(map[uint32]*csvAidToNavigation),
+	}, nil
+}
+
+func (writer *MultiTableCsvAISRecordWriter) WriteAISRecord(record *AISRecord) error {
+	packet := record.AIS.Packet
+	userID := packet.GetHeader().UserID
+
+	position, ok := aisVesselPositionOf(packet)
+	if ok {
+		vessel := writer.getVessel(userID)
+		vessel.add(record.Timestamp)
+		vessel.class = position.Class
+		err := writer.positionsWriter.Write(writer.options.row(writer.positionColumns, &csvRow{
+			Timestamp:  record.Timestamp,
+			UserID:     userID,
+			Position:   position,
+			StaticData: vessel.staticData,
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch report := packet.(type) {
+	case ais.ShipStaticData, ais.ExtendedClassBPositionReport, ais.StaticDataReport:
+		vessel := writer.getVessel(userID)
+		if vessel.staticData == nil {
+			vessel.staticData = &aisStaticData{
+				UserID: userID,
+			}
+		}
+		vessel.staticData.update(report)
+		if vessel.class == "" {
+			vessel.class = vessel.staticData.Class
+		}
+
+	case ais.BaseStationReport:
+		if report.MessageID != 4 {
+			break
+		}
+		baseStation, ok := writer.baseStationMap[userID]
+		if !ok {
+			baseStation = &csvBaseStation{}
+			writer.baseStationMap[userID] = baseStation
+		}
+		baseStation.add(record.Timestamp)
+		baseStation.report = report
+
+	case ais.AidsToNavigationReport:
+		aton, ok := writer.atonMap[userID]
+		if !ok {
+			aton = &csvAidToNavigation{}
+			writer.atonMap[userID] = aton
+		}
+		aton.add(record.Timestamp)
+		aton.report = report
+	}
+
+	return nil
+}
+
+func (writer *MultiTableCsvAISRecordWriter) getVessel(userID uint32) *csvVessel {
+	vessel, ok := writer.vesselMap[userID]
+	if !ok {
+		vessel = &csvVessel{}
+		writer.vesselMap[userID] = vessel
+	}
+	return vessel
+}
+
+func (writer *MultiTableCsvAISRecordWriter) Close() error {
+	writer.positionsWriter.Flush()
+	err := writer.positionsWriter.Error()
+	if err != nil {
+		return err
+	}
+
+	options := &writer.options
+	staticDataColumnNames := csvVesselStaticDataColumns
+	stationColumnNames := []string{"mmsi"}
+	if options.Enrich {
+		staticDataColumnNames = append(slices.Clone(staticDataColumnNames), "flag", "country", "stationclass", "aistypetext")
+		stationColumnNames = append(stationColumnNames, "flag", "country", "stationclass")
+	}
+	staticDataColumns, err := options.columnsOf(staticDataColumnNames, nil)
+	if err != nil {
+		return err
+	}
+	stationColumns, err := options.columnsOf(stationColumnNames, nil)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"MMSI", "CLASS"}
+	header = append(header, options.header(staticDataColumns)...)
+	header = append(header, csvSightingHeader(options)...)
+	err = writer.writeTable(CsvVesselsTable, header, sortedUserIDs(writer.vesselMap), func(userID uint32) []string {
+		vessel := writer.vesselMap[userID]
+		cells := []string{
+			strconv.FormatInt(int64(userID), 10),
+			vessel.class,
+		}
+		cells = append(cells, options.row(staticDataColumns, &csvRow{
+			UserID:     userID,
+			StaticData: vessel.staticData,
+		})...)
+		return append(cells, vessel.cells(options)...)
+	})
+	if err != nil {
+		return err
+	}
+
+	header = options.header(stationColumns)
+	header = append(header, "LATITUDE", "LONGITUDE", "FIXTYPE")
+	header = append(header, csvSightingHeader(options)...)
+	err = writer.writeTable(CsvBaseStationsTable, header, sortedUserIDs(writer.baseStationMap), func(userID uint32) []string {
+		baseStation := writer.baseStationMap[userID]
+		cells := options.row(stationColumns, &csvRow{
+			UserID: userID,
+		})
+		cells = append(cells,
+			options.formatLatitude(float64(baseStation.report.Latitude)),
+			options.formatLongitude(float64(baseStation.report.Longitude)),
+			strconv.FormatInt(int64(baseStation.report.FixType), 10),
+		)
+		return append(cells, baseStation.cells(options)...)
+	})
+	if err != nil {
+		return err
+	}
+
+	header = options.header(stationColumns)
+	header = append(header, "NAME", "ATONTYPE", "VIRTUAL", "LATITUDE", "LONGITUDE", "A", "B", "C", "D", "OFFPOSITION")
+	header = append(header, csvSightingHeader(options)...)
+	return writer.writeTable(CsvAidsToNavigationTable, header, sortedUserIDs(writer.atonMap), func(userID uint32) []string {
+		aton := writer.atonMap[userID]
+		report := aton.report
+		cells := options.row(stationColumns, &csvRow{
+			UserID: userID,
+		})
+		cells = append(cells,
+			report.Name+report.NameExtension,
+			strconv.FormatInt(int64(report.Type), 10),
+			strconv.FormatBool(report.VirtualAtoN),
+			options.formatLatitude(float64(report.Latitude)),
+			options.formatLongitude(float64(report.Longitude)),
+			strconv.FormatInt(int64(report.Dimension.A), 10),
+			strconv.FormatInt(int64(report.Dimension.B), 10),
+			strconv.FormatInt(int64(report.Dimension.C), 10),
+			strconv.FormatInt(int64(report.Dimension.D), 10),
+			strconv.FormatBool(report.OffPosition),
+		)
+		return append(cells, aton.cells(options)...)
+	})
+}
+
+func (writer *MultiTableCsvAISRecordWriter) writeTable(name string, header []string, userIDs []uint32, row func(userID uint32) []string) error {
+	w, err := writer.tableCreator.Create(name)
+	if err != nil {
+		return err
+	}
+	csvWriter := writer.options.newWriter(w)
+	err = csvWriter.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		err = csvWriter.Write(row(userID))
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func sortedUserIDs[T any](m map[uint32]T) []uint32 {
+	userIDs := make([]uint32, 0, len(m))
+	for userID := range m {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		return userIDs[i] < userIDs[j]
+	})
+	return userIDs
+}
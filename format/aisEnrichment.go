@@ -0,0 +1,46 @@
+package format
+
+import (
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/aiscode"
+)
+
+// AISEnrichment holds human-readable context for an AIS message: the station class and flag state decoded from the
+// MMSI, and the descriptions of the ship type and navigational status codes, if the message carries them.
+type AISEnrichment struct {
+	StationClass           aiscode.StationClass `json:"stationClass"`
+	Flag                   *aiscode.Country     `json:"flag,omitempty"`
+	ShipTypeText           string               `json:"shipTypeText,omitempty"`
+	NavigationalStatusText string               `json:"navigationalStatusText,omitempty"`
+}
+
+// NewAISEnrichment returns the enrichment of a packet, or nil if the packet could not be decoded.
+func NewAISEnrichment(packet ais.Packet) *AISEnrichment {
+	if packet == nil {
+		return nil
+	}
+	mmsi := packet.GetHeader().UserID
+	stationClass, _ := aiscode.Classify(mmsi)
+	enrichment := &AISEnrichment{
+		StationClass: stationClass,
+	}
+	country, ok := aiscode.Flag(mmsi)
+	if ok {
+		enrichment.Flag = &country
+	}
+	switch report := packet.(type) {
+	case ais.PositionReport:
+		enrichment.NavigationalStatusText = aiscode.NavigationalStatusText(report.NavigationalStatus)
+	case ais.LongRangeAisBroadcastMessage:
+		enrichment.NavigationalStatusText = aiscode.NavigationalStatusText(report.NavigationalStatus)
+	case ais.ShipStaticData:
+		enrichment.ShipTypeText = aiscode.ShipTypeText(report.Type)
+	case ais.ExtendedClassBPositionReport:
+		enrichment.ShipTypeText = aiscode.ShipTypeText(report.Type)
+	case ais.StaticDataReport:
+		if report.ReportB.Valid {
+			enrichment.ShipTypeText = aiscode.ShipTypeText(report.ReportB.ShipType)
+		}
+	}
+	return enrichment
+}
@@ -0,0 +1,101 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+type geoJsonFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*geoJsonFeature `json:"features"`
+}
+
+type geoJsonFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJsonGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJsonGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJsonAISRecordWriter writes one LineString feature per vessel. Tracks are buffered in memory and written on Close.
+type GeoJsonAISRecordWriter struct {
+	w         io.Writer
+	collector *aisTrackCollector
+}
+
+func NewGeoJsonAISRecordWriter(w io.Writer) *GeoJsonAISRecordWriter {
+	return &GeoJsonAISRecordWriter{
+		w:         w,
+		collector: newAISTrackCollector(),
+	}
+}
+
+func (writer *GeoJsonAISRecordWriter) Close() error {
+	featureCollection := geoJsonFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []*geoJsonFeature{},
+	}
+	for _, track := range writer.collector.tracks() {
+		var coordinates [][]float64
+		var times []string
+		for _, point := range track.Points {
+			coordinates = append(coordinates, []float64{
+				roundToDecimalPoints(point.Longitude, 6),
+				roundToDecimalPoints(point.Latitude, 6),
+			})
+			times = append(times, time.UnixMilli(point.Timestamp).UTC().Format(time.RFC3339Nano))
+		}
+		geometry := geoJsonGeometry{
+			Type:        "LineString",
+			Coordinates: coordinates,
+		}
+		if len(coordinates) == 1 {
+			geometry = geoJsonGeometry{
+				Type:        "Point",
+				Coordinates: coordinates[0],
+			}
+		}
+		properties := aisTrackProperties(track)
+		properties["times"] = times
+		featureCollection.Features = append(featureCollection.Features, &geoJsonFeature{
+			Type:       "Feature",
+			Geometry:   geometry,
+			Properties: properties,
+		})
+	}
+
+	jsonEncoder := json.NewEncoder(writer.w)
+	return jsonEncoder.Encode(featureCollection)
+}
+
+func (writer *GeoJsonAISRecordWriter) WriteAISRecord(record *AISRecord) error {
+	writer.collector.add(record)
+	return nil
+}
+
+func aisTrackProperties(track *aisTrack) map[string]any {
+	properties := map[string]any{
+		"mmsi":      track.UserID,
+		"firstSeen": time.UnixMilli(track.Points[0].Timestamp).UTC().Format(time.RFC3339Nano),
+		"lastSeen":  time.UnixMilli(track.Points[len(track.Points)-1].Timestamp).UTC().Format(time.RFC3339Nano),
+		"points":    len(track.Points),
+	}
+	shipStaticData := track.ShipStaticData
+	if shipStaticData != nil {
+		properties["imo"] = shipStaticData.ImoNumber
+		properties["name"] = strings.TrimSpace(shipStaticData.Name)
+		properties["callSign"] = strings.TrimSpace(shipStaticData.CallSign)
+		properties["shipType"] = shipStaticData.Type
+		properties["length"] = int(shipStaticData.Dimension.A) + int(shipStaticData.Dimension.B)
+		properties["beam"] = int(shipStaticData.Dimension.C) + int(shipStaticData.Dimension.D)
+		properties["draught"] = float64(shipStaticData.MaximumStaticDraught)
+		properties["destination"] = strings.TrimSpace(shipStaticData.Destination)
+	}
+	return properties
+}
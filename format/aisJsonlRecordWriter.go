@@ -1,11 +1,15 @@
 package format
 
 import (
+	"encoding/json"
 	"io"
 )
 
+// JsonlAISRecordWriter writes one AISMessage per line.
 type JsonlAISRecordWriter struct {
 	jsonlWriter *JsonlWriter
+	enrich      bool
+	raw         bool
 }
 
 func NewJsonlAISRecordWriter(w io.Writer) *JsonlAISRecordWriter {
@@ -14,10 +18,35 @@ func NewJsonlAISRecordWriter(w io.Writer) *JsonlAISRecordWriter {
 	}
 }
 
+// SetEnrichment enables or disables adding an AISEnrichment to each message.
+func (writer *JsonlAISRecordWriter) SetEnrichment(enrich bool) {
+	writer.enrich = enrich
+}
+
+// SetRaw enables or disables writing AIS "not available" values as is. By default, they are written as null.
+func (writer *JsonlAISRecordWriter) SetRaw(raw bool) {
+	writer.raw = raw
+}
+
 func (writer *JsonlAISRecordWriter) Close() error {
 	return writer.jsonlWriter.Close()
 }
 
 func (writer *JsonlAISRecordWriter) WriteAISRecord(record *AISRecord) error {
-	return writer.jsonlWriter.WriteRecord(record)
+	message := NewAISMessage(record)
+	if writer.enrich {
+		message.Enrichment = NewAISEnrichment(record.AIS.Packet)
+	}
+	if writer.raw {
+		return writer.jsonlWriter.WriteRecord(message)
+	}
+	jsonBytes, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	jsonBytes, err = NullJSONFields(jsonBytes, aisMessageNotAvailableFields(record.AIS.Packet))
+	if err != nil {
+		return err
+	}
+	return writer.jsonlWriter.WriteRecord(json.RawMessage(jsonBytes))
 }
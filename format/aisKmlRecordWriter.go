@@ -0,0 +1,102 @@
+package format
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type kmlDocument struct {
+	XMLName    xml.Name        `xml:"kml"`
+	Xmlns      string          `xml:"xmlns,attr"`
+	XmlnsGx    string          `xml:"xmlns:gx,attr"`
+	Name       string          `xml:"Document>name"`
+	Placemarks []*kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string    `xml:"name"`
+	ExtendedData []kmlData `xml:"ExtendedData>Data"`
+	Track        kmlTrack  `xml:"gx:Track"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"gx:coord"`
+}
+
+// KmlAISRecordWriter writes one gx:Track placemark per vessel. Tracks are buffered in memory and written on Close.
+type KmlAISRecordWriter struct {
+	w         io.Writer
+	collector *aisTrackCollector
+}
+
+func NewKmlAISRecordWriter(w io.Writer) *KmlAISRecordWriter {
+	return &KmlAISRecordWriter{
+		w:         w,
+		collector: newAISTrackCollector(),
+	}
+}
+
+func (writer *KmlAISRecordWriter) Close() error {
+	document := kmlDocument{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+		Name:    "AIS tracks",
+	}
+	for _, track := range writer.collector.tracks() {
+		properties := aisTrackProperties(track)
+		name := strconv.FormatUint(uint64(track.UserID), 10)
+		if shipName, ok := properties["name"].(string); ok && (shipName != "") {
+			name = shipName
+		}
+		placemark := &kmlPlacemark{
+			Name: name,
+		}
+		var keys []string
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			placemark.ExtendedData = append(placemark.ExtendedData, kmlData{
+				Name:  key,
+				Value: fmt.Sprint(properties[key]),
+			})
+		}
+		for _, point := range track.Points {
+			placemark.Track.When = append(placemark.Track.When, time.UnixMilli(point.Timestamp).UTC().Format(time.RFC3339Nano))
+			placemark.Track.Coord = append(placemark.Track.Coord, fmt.Sprintf("%s %s 0",
+				strconv.FormatFloat(roundToDecimalPoints(point.Longitude, 6), 'f', -1, 64),
+				strconv.FormatFloat(roundToDecimalPoints(point.Latitude, 6), 'f', -1, 64),
+			))
+		}
+		document.Placemarks = append(document.Placemarks, placemark)
+	}
+
+	_, err := io.WriteString(writer.w, xml.Header)
+	if err != nil {
+		return err
+	}
+	xmlEncoder := xml.NewEncoder(writer.w)
+	xmlEncoder.Indent("", "  ")
+	err = xmlEncoder.Encode(document)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer.w, "\n")
+	return err
+}
+
+func (writer *KmlAISRecordWriter) WriteAISRecord(record *AISRecord) error {
+	writer.collector.add(record)
+	return nil
+}
@@ -0,0 +1,356 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BertoldVdb/go-ais"
+)
+
+// AISMessageSchemaVersion is the version of the AISMessage schema. It is incremented when a field is removed, renamed
+// or changes meaning; adding fields does not change the version.
+const AISMessageSchemaVersion = 1
+
+// AISMessage is a decoded AIS message in the flattened, versioned schema of JSONL output. Unlike the go-ais packet
+// types, its layout is stable across decoder versions. Typed fields that are not carried by the message type are
+// omitted.
+type AISMessage struct {
+	SchemaVersion   int      `json:"schemaVersion" description:"Version of the record schema."`
+	Timestamp       int64    `json:"timestamp" description:"Receive time, in milliseconds since the Unix epoch."`
+	MessageType     uint8    `json:"messageType" description:"AIS message type (1-27)."`
+	RepeatIndicator uint8    `json:"repeatIndicator" description:"Number of times the message has been repeated."`
+	MMSI            uint32   `json:"mmsi" description:"MMSI of the transmitting station."`
+	Channel         string   `json:"channel" description:"AIS channel (A or B)."`
+	TalkerID        string   `json:"talkerId" description:"NMEA talker ID of the receiver (e.g. AI)."`
+	Sources         []string `json:"sources,omitempty" description:"Receivers of the message, if duplicate messages were removed."`
+
+	NavigationalStatus *uint8   `json:"navigationalStatus,omitempty" description:"Navigational status code (types 1-3 and 27)."`
+	RateOfTurn         *int16   `json:"rateOfTurn,omitempty" nullable:"true" description:"Rate of turn indicator, -127 to 127 (types 1-3). Null if not available."`
+	Sog                *float64 `json:"sog,omitempty" nullable:"true" description:"Speed over ground, in knots (types 1-3, 9, 18, 19 and 27). Null if not available."`
+	Cog                *float64 `json:"cog,omitempty" nullable:"true" description:"Course over ground, in degrees (types 1-3, 9, 18, 19 and 27). Null if not available."`
+	TrueHeading        *uint16  `json:"trueHeading,omitempty" nullable:"true" description:"True heading, in degrees (types 1-3, 18 and 19). Null if not available."`
+	Latitude           *float64 `json:"latitude,omitempty" nullable:"true" description:"Latitude, in degrees (types 1-4, 9, 11, 17-19, 21 and 27). Null if not available."`
+	Longitude          *float64 `json:"longitude,omitempty" nullable:"true" description:"Longitude, in degrees (types 1-4, 9, 11, 17-19, 21 and 27). Null if not available."`
+	PositionAccuracy   *bool    `json:"positionAccuracy,omitempty" description:"True if the position accuracy is better than 10 m."`
+	UtcSecond          *uint8   `json:"utcSecond,omitempty" description:"UTC second of the position fix (60 if not available)."`
+	Altitude           *uint16  `json:"altitude,omitempty" nullable:"true" description:"Altitude, in metres (type 9). Null if not available."`
+	UtcTime            *string  `json:"utcTime,omitempty" description:"UTC date and time reported by the station, as YYYY-MM-DDThh:mm:ssZ (types 4 and 11)."`
+	FixType            *uint8   `json:"fixType,omitempty" description:"Type of electronic position fixing device."`
+	Raim               *bool    `json:"raim,omitempty" description:"True if RAIM is in use."`
+
+	ImoNumber   *uint32              `json:"imo,omitempty" description:"IMO number (type 5)."`
+	CallSign    *string              `json:"callSign,omitempty" description:"Call sign (types 5 and 24)."`
+	Name        *string              `json:"name,omitempty" description:"Vessel or aid to navigation name (types 5, 19, 21 and 24)."`
+	ShipType    *uint8               `json:"shipType,omitempty" description:"Ship and cargo type code (types 5, 19 and 24)."`
+	Dimension   *AISMessageDimension `json:"dimension,omitempty" description:"Dimensions relative to the position reference point (types 5, 19, 21 and 24)."`
+	Draught     *float64             `json:"draught,omitempty" description:"Maximum present static draught, in metres (type 5)."`
+	Destination *string              `json:"destination,omitempty" description:"Destination (type 5)."`
+	Eta         *AISMessageEta       `json:"eta,omitempty" description:"Estimated time of arrival, in UTC (type 5)."`
+	Dte         *bool                `json:"dte,omitempty" description:"True if data terminal equipment is not available (types 5, 9 and 19)."`
+	PartNumber  *uint8               `json:"partNumber,omitempty" description:"Part of a static data report (type 24): 0 for part A, 1 for part B."`
+	VendorID    *string              `json:"vendorId,omitempty" description:"Vendor ID (type 24 part B)."`
+
+	AtoNType        *uint8 `json:"atonType,omitempty" description:"Type of aid to navigation (type 21)."`
+	VirtualAtoN     *bool  `json:"virtualAtoN,omitempty" description:"True if the aid to navigation is virtual (type 21)."`
+	OffPosition     *bool  `json:"offPosition,omitempty" description:"True if a floating aid to navigation is off position (type 21)."`
+	PositionLatency *bool  `json:"positionLatency,omitempty" description:"True if the position is older than 5 seconds (type 27)."`
+
+	DestinationID  *uint32                  `json:"destinationId,omitempty" description:"MMSI of the addressed station (types 6, 10 and 12, and 25 and 26 if addressed)."`
+	SequenceNumber *uint8                   `json:"sequenceNumber,omitempty" description:"Sequence number of an addressed message (types 6 and 12)."`
+	Retransmission *bool                    `json:"retransmission,omitempty" description:"True if an addressed message is retransmitted (types 6 and 12)."`
+	ApplicationID  *AISMessageApplicationID `json:"applicationId,omitempty" description:"Application identifier of binary data (types 6 and 8, and 25 and 26 if present)."`
+	BinaryData     *string                  `json:"binaryData,omitempty" description:"Binary data, as a string of 0 and 1 bits (types 6, 8, 17, 25 and 26)."`
+	Text           *string                  `json:"text,omitempty" description:"Safety related text (types 12 and 14)."`
+	Payload        *string                  `json:"payload,omitempty" description:"Complete AIS payload, as a string of 0 and 1 bits, for message types whose content is not carried by the typed fields (types 7, 13, 15, 16, 20, 22 and 23, unknown types, and payloads that cannot be decoded)."`
+
+	Enrichment *AISEnrichment `json:"enrichment,omitempty" description:"Decoded station class, flag state and code descriptions (with --enrich)."`
+}
+
+type AISMessageDimension struct {
+	A uint16 `json:"a" description:"Distance to bow, in metres."`
+	B uint16 `json:"b" description:"Distance to stern, in metres."`
+	C uint8  `json:"c" description:"Distance to port, in metres."`
+	D uint8  `json:"d" description:"Distance to starboard, in metres."`
+}
+
+type AISMessageApplicationID struct {
+	DesignatedAreaCode uint16 `json:"dac" description:"Designated area code (DAC)."`
+	FunctionIdentifier uint8  `json:"fi" description:"Function identifier (FI)."`
+}
+
+type AISMessageEta struct {
+	Month  uint8 `json:"month" description:"Month (1-12, 0 if not available)."`
+	Day    uint8 `json:"day" description:"Day (1-31, 0 if not available)."`
+	Hour   uint8 `json:"hour" description:"Hour (0-23, 24 if not available)."`
+	Minute uint8 `json:"minute" description:"Minute (0-59, 60 if not available)."`
+}
+
+// NewAISMessage returns the AISMessage of a record. If the payload cannot be decoded, the message carries the message
+// type and MMSI read from the payload (if long enough), and the payload itself.
+func NewAISMessage(record *AISRecord) *AISMessage {
+	packet := record.AIS.Packet
+	message := &AISMessage{
+		SchemaVersion: AISMessageSchemaVersion,
+		Timestamp:     record.Timestamp,
+		Channel:       aisChannelName(record.AIS.Channel),
+		TalkerID:      record.AIS.TalkerID,
+		Sources:       record.Sources,
+	}
+	if packet == nil {
+		message.MessageType, message.RepeatIndicator, message.MMSI = aisPayloadHeader(record.AIS.Payload)
+		message.Payload = aisBitString(record.AIS.Payload)
+		return message
+	}
+	header := packet.GetHeader()
+	message.MessageType = header.MessageID
+	message.RepeatIndicator = header.RepeatIndicator
+	message.MMSI = header.UserID
+
+	switch p := packet.(type) {
+	case ais.PositionReport:
+		message.NavigationalStatus = &p.NavigationalStatus
+		message.RateOfTurn = &p.RateOfTurn
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.setCourse(float64(p.Sog), float64(p.Cog))
+		message.TrueHeading = &p.TrueHeading
+		message.UtcSecond = &p.Timestamp
+
+	case ais.BaseStationReport:
+		utcTime := fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02dZ", p.UtcYear, p.UtcMonth, p.UtcDay, p.UtcHour, p.UtcMinute, p.UtcSecond)
+		message.UtcTime = &utcTime
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.FixType = &p.FixType
+
+	case ais.ShipStaticData:
+		message.ImoNumber = &p.ImoNumber
+		message.CallSign = trimmedString(p.CallSign)
+		message.Name = trimmedString(p.Name)
+		message.ShipType = &p.Type
+		message.Dimension = newAISMessageDimension(p.Dimension)
+		message.FixType = &p.FixType
+		message.Eta = &AISMessageEta{
+			Month:  p.Eta.Month,
+			Day:    p.Eta.Day,
+			Hour:   p.Eta.Hour,
+			Minute: p.Eta.Minute,
+		}
+		draught := float64(p.MaximumStaticDraught)
+		message.Draught = &draught
+		message.Destination = trimmedString(p.Destination)
+		message.Dte = &p.Dte
+
+	case ais.StandardSearchAndRescueAircraftReport:
+		message.Altitude = &p.Altitude
+		sog := float64(p.Sog)
+		message.Sog = &sog
+		cog := float64(p.Cog)
+		message.Cog = &cog
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.UtcSecond = &p.Timestamp
+		message.Dte = &p.Dte
+
+	case ais.StandardClassBPositionReport:
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.setCourse(float64(p.Sog), float64(p.Cog))
+		message.TrueHeading = &p.TrueHeading
+		message.UtcSecond = &p.Timestamp
+
+	case ais.ExtendedClassBPositionReport:
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.setCourse(float64(p.Sog), float64(p.Cog))
+		message.TrueHeading = &p.TrueHeading
+		message.UtcSecond = &p.Timestamp
+		message.Name = trimmedString(p.Name)
+		message.ShipType = &p.Type
+		message.Dimension = newAISMessageDimension(p.Dimension)
+		message.FixType = &p.FixType
+		message.Dte = &p.Dte
+
+	case ais.AidsToNavigationReport:
+		message.AtoNType = &p.Type
+		message.Name = trimmedString(p.Name + p.NameExtension)
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		message.Dimension = newAISMessageDimension(p.Dimension)
+		message.FixType = &p.Fixtype
+		message.UtcSecond = &p.Timestamp
+		message.OffPosition = &p.OffPosition
+		message.VirtualAtoN = &p.VirtualAtoN
+
+	case ais.StaticDataReport:
+		var partNumber uint8
+		if p.PartNumber {
+			partNumber = 1
+		}
+		message.PartNumber = &partNumber
+		if p.ReportA.Valid {
+			message.Name = trimmedString(p.ReportA.Name)
+		}
+		if p.ReportB.Valid {
+			message.ShipType = &p.ReportB.ShipType
+			message.VendorID = trimmedString(p.ReportB.VendorIDName)
+			message.CallSign = trimmedString(p.ReportB.CallSign)
+			message.Dimension = newAISMessageDimension(p.ReportB.Dimension)
+			message.FixType = &p.ReportB.FixType
+		}
+
+	case ais.LongRangeAisBroadcastMessage:
+		message.NavigationalStatus = &p.NavigationalStatus
+		message.setPosition(float64(p.Latitude), float64(p.Longitude), p.PositionAccuracy, p.Raim)
+		sog := float64(p.Sog)
+		message.Sog = &sog
+		cog := float64(p.Cog)
+		message.Cog = &cog
+		message.PositionLatency = &p.PositionLatency
+
+	case ais.AddressedBinaryMessage:
+		message.SequenceNumber = &p.SequenceNumber
+		message.DestinationID = &p.DestinationID
+		message.Retransmission = &p.Retransmission
+		message.ApplicationID = newAISMessageApplicationID(p.ApplicationID)
+		message.BinaryData = aisBitString(p.BinaryData)
+
+	case ais.BinaryBroadcastMessage:
+		message.ApplicationID = newAISMessageApplicationID(p.ApplicationID)
+		message.BinaryData = aisBitString(p.BinaryData)
+
+	case ais.CoordinatedUTCInquiry:
+		message.DestinationID = &p.DestinationID
+
+	case ais.AddessedSafetyMessage:
+		message.SequenceNumber = &p.SequenceNumber
+		message.DestinationID = &p.DestinationID
+		message.Retransmission = &p.Retransmission
+		message.Text = trimmedString(p.Text)
+
+	case ais.SafetyBroadcastMessage:
+		message.Text = trimmedString(p.Text)
+
+	case ais.GnssBroadcastBinaryMessage:
+		latitude := float64(p.Latitude)
+		longitude := float64(p.Longitude)
+		message.Latitude = &latitude
+		message.Longitude = &longitude
+		message.BinaryData = aisBitString(p.Data)
+
+	case ais.SingleSlotBinaryMessage:
+		if p.DestinationIDValid {
+			message.DestinationID = &p.DestinationID
+		}
+		if p.ApplicationIDValid {
+			message.ApplicationID = newAISMessageApplicationID(p.ApplicationID)
+		}
+		message.BinaryData = aisBitString(p.Payload)
+
+	case ais.MultiSlotBinaryMessage:
+		if p.DestinationIDValid {
+			message.DestinationID = &p.DestinationID
+		}
+		if p.ApplicationIDValid {
+			message.ApplicationID = newAISMessageApplicationID(p.ApplicationID)
+		}
+		message.BinaryData = aisBitString(p.Payload)
+
+	default:
+		message.Payload = aisBitString(record.AIS.Payload)
+	}
+
+	return message
+}
+
+func newAISMessageApplicationID(applicationID ais.FieldApplicationIdentifier) *AISMessageApplicationID {
+	return &AISMessageApplicationID{
+		DesignatedAreaCode: applicationID.DesignatedAreaCode,
+		FunctionIdentifier: applicationID.FunctionIdentifier,
+	}
+}
+
+// aisBitString returns bits, as decoded by go-ais (one bit per byte), as a string of 0 and 1 characters.
+func aisBitString(bits []byte) *string {
+	var sb strings.Builder
+	sb.Grow(len(bits))
+	for _, bit := range bits {
+		sb.WriteByte('0' + bit&1)
+	}
+	s := sb.String()
+	return &s
+}
+
+// aisPayloadHeader reads the message type, repeat indicator and MMSI from a payload (one bit per byte). Fields that the
+// payload is too short to hold are 0.
+func aisPayloadHeader(bits []byte) (messageType uint8, repeatIndicator uint8, mmsi uint32) {
+	readBits := func(offset int, width int) uint32 {
+		var v uint32
+		for i := offset; i < offset+width; i++ {
+			v = v<<1 | uint32(bits[i]&1)
+		}
+		return v
+	}
+	if len(bits) >= 6 {
+		messageType = uint8(readBits(0, 6))
+	}
+	if len(bits) >= 8 {
+		repeatIndicator = uint8(readBits(6, 2))
+	}
+	if len(bits) >= 38 {
+		mmsi = readBits(8, 30)
+	}
+	return messageType, repeatIndicator, mmsi
+}
+
+func (message *AISMessage) setPosition(latitude float64, longitude float64, positionAccuracy bool, raim bool) {
+	message.Latitude = &latitude
+	message.Longitude = &longitude
+	message.PositionAccuracy = &positionAccuracy
+	message.Raim = &raim
+}
+
+func (message *AISMessage) setCourse(sog float64, cog float64) {
+	message.Sog = &sog
+	message.Cog = &cog
+}
+
+func newAISMessageDimension(dimension ais.FieldDimension) *AISMessageDimension {
+	return &AISMessageDimension{
+		A: dimension.A,
+		B: dimension.B,
+		C: dimension.C,
+		D: dimension.D,
+	}
+}
+
+func trimmedString(s string) *string {
+	s = strings.TrimSpace(s)
+	return &s
+}
+
+// aisChannelName returns the name of a channel as numbered by aisnmea.VdmPacket (1 for A, 2 for B).
+func aisChannelName(channel byte) string {
+	switch channel {
+	case 1:
+		return "A"
+	case 2:
+		return "B"
+	}
+	return ""
+}
+
+// aisMessageNotAvailableFields returns the names of the AISMessage fields of packet that hold a "not available" value.
+func aisMessageNotAvailableFields(packet ais.Packet) []string {
+	var fields []string
+	for _, field := range AISNotAvailableFields(packet) {
+		r, n := utf8.DecodeRuneInString(field)
+		fields = append(fields, string(unicode.ToLower(r))+field[n:])
+	}
+	return fields
+}
+
+// AISMessageJSONSchema returns the JSON Schema of AISMessage.
+func AISMessageJSONSchema() map[string]any {
+	schema := JSONSchema(AISMessage{}, "AIS message")
+	schema["description"] = fmt.Sprintf("Decoded AIS message, as written by ais convert in JSONL format (schema version %d).", AISMessageSchemaVersion)
+	properties := schema["properties"].(map[string]any)
+	properties["schemaVersion"].(map[string]any)["const"] = AISMessageSchemaVersion
+	return schema
+}
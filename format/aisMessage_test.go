@@ -0,0 +1,90 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BertoldVdb/go-ais/aisnmea"
+)
+
+func TestNewAISMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		want     string
+	}{
+		{
+			"binary broadcast",
+			"!AIVDM,1,1,,A,85Mwp`1Kf3aCnsNvBWLi=wQuNhA5t43N`5nCuI=p<IBfVqnMgPGs,0*47",
+			`{"messageType":8,"mmsi":366999712,"applicationId":{"dac":366,"fi":56}}`,
+		},
+		{
+			"safety broadcast",
+			"!AIVDM,1,1,,A,>5?Per18=HB1U:1@E=B0m<L,2*51",
+			`{"messageType":14,"mmsi":351809000,"text":"RCVD YR TEST MSG"}`,
+		},
+		{
+			"undecodable payload",
+			"!AIVDM,1,1,,A,13u?etP00,0*2F",
+			`{"messageType":1,"mmsi":265547250,"payload":"000001000011111101001111101101111100100000000000000000"}`,
+		},
+		{
+			"payload shorter than the header",
+			"!AIVDM,1,1,,A,?,0*19",
+			`{"messageType":15,"mmsi":0,"payload":"001111"}`,
+		},
+	}
+	decoder := NewAISDecoder()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vdmPacket, err := decoder.ParseSentence(test.sentence)
+			if err != nil {
+				t.Fatalf("ParseSentence() error = %v", err)
+			}
+			message := NewAISMessage(&AISRecord{
+				AIS: vdmPacket,
+			})
+			assertJSONSubset(t, message, test.want)
+		})
+	}
+}
+
+func TestNewAISMessageWithoutPacket(t *testing.T) {
+	message := NewAISMessage(&AISRecord{
+		AIS: &aisnmea.VdmPacket{
+			Payload: []byte{0, 0, 1, 1, 1, 1},
+		},
+	})
+	if (message.Payload == nil) || (*message.Payload != "001111") {
+		t.Errorf("Payload = %v, want 001111", message.Payload)
+	}
+	if NewAISEnrichment(nil) != nil {
+		t.Errorf("NewAISEnrichment(nil) != nil")
+	}
+}
+
+// assertJSONSubset checks that the JSON encoding of v holds the fields of want, with the same values.
+func assertJSONSubset(t *testing.T, v any, want string) {
+	t.Helper()
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotFields map[string]any
+	err = json.Unmarshal(jsonBytes, &gotFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantFields map[string]any
+	err = json.Unmarshal([]byte(want), &wantFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, wantValue := range wantFields {
+		wantJSON, _ := json.Marshal(wantValue)
+		gotJSON, _ := json.Marshal(gotFields[key])
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s = %s, want %s", key, gotJSON, wantJSON)
+		}
+	}
+}
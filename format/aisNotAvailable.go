@@ -0,0 +1,185 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/BertoldVdb/go-ais"
+)
+
+// AIS encodes a missing value as an out-of-range "not available" value, e.g. latitude 91 or heading 511.
+
+func isLatitudeAvailable(latitude float64) bool {
+	return (latitude >= -90) && (latitude <= 90)
+}
+
+func isLongitudeAvailable(longitude float64) bool {
+	return (longitude >= -180) && (longitude <= 180)
+}
+
+// isSogAvailable reports whether a speed over ground in knots, as reported by types 1-3, 9, 18 and 19, is available.
+// 102.3 knots is "not available"; 102.2 knots means 102.2 knots or higher.
+func isSogAvailable(sog float64) bool {
+	return math.Round(sog*10) < 1023
+}
+
+// isCogAvailable reports whether a course over ground in degrees, as reported by types 1-3, 9, 18 and 19, is
+// available. 360 degrees is "not available".
+func isCogAvailable(cog float64) bool {
+	return math.Round(cog*10) < 3600
+}
+
+func isHeadingAvailable(heading uint16) bool {
+	return heading != 511
+}
+
+// AISNotAvailableFields returns the names of the fields of packet that hold a "not available" value.
+func AISNotAvailableFields(packet ais.Packet) []string {
+	var fields []string
+	add := func(name string, available bool) {
+		if !available {
+			fields = append(fields, name)
+		}
+	}
+	switch p := packet.(type) {
+	case ais.PositionReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+		add("Sog", isSogAvailable(float64(p.Sog)))
+		add("Cog", isCogAvailable(float64(p.Cog)))
+		add("TrueHeading", isHeadingAvailable(p.TrueHeading))
+		add("RateOfTurn", p.RateOfTurn != -128)
+	case ais.BaseStationReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+	case ais.StandardSearchAndRescueAircraftReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+		add("Sog", p.Sog != 1023)
+		add("Cog", isCogAvailable(float64(p.Cog)))
+		add("Altitude", p.Altitude != 4095)
+	case ais.StandardClassBPositionReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+		add("Sog", isSogAvailable(float64(p.Sog)))
+		add("Cog", isCogAvailable(float64(p.Cog)))
+		add("TrueHeading", isHeadingAvailable(p.TrueHeading))
+	case ais.ExtendedClassBPositionReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+		add("Sog", isSogAvailable(float64(p.Sog)))
+		add("Cog", isCogAvailable(float64(p.Cog)))
+		add("TrueHeading", isHeadingAvailable(p.TrueHeading))
+	case ais.AidsToNavigationReport:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+	case ais.GnssBroadcastBinaryMessage:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+	case ais.LongRangeAisBroadcastMessage:
+		add("Latitude", isLatitudeAvailable(float64(p.Latitude)))
+		add("Longitude", isLongitudeAvailable(float64(p.Longitude)))
+		add("Sog", p.Sog != 63)
+		add("Cog", p.Cog != 511)
+	}
+	return fields
+}
+
+// NullJSONFields replaces the values of the named fields of a JSON document with null. Fields are matched by name at
+// any depth.
+func NullJSONFields(data []byte, fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	fieldMap := make(map[string]bool)
+	for _, field := range fields {
+		fieldMap[field] = true
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var buf bytes.Buffer
+	err := copyJSONValue(decoder, &buf, fieldMap)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func copyJSONValue(decoder *json.Decoder, buf *bytes.Buffer, fieldMap map[string]bool) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		buf.WriteByte('{')
+		for i := 0; decoder.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			token, err = decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, ok := token.(string)
+			if !ok {
+				return fmt.Errorf("invalid object key")
+			}
+			err = writeJSONScalar(buf, key)
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if fieldMap[key] {
+				var value json.RawMessage
+				err = decoder.Decode(&value)
+				if err != nil {
+					return err
+				}
+				buf.WriteString("null")
+				continue
+			}
+			err = copyJSONValue(decoder, buf, fieldMap)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+
+	case json.Delim('['):
+		buf.WriteByte('[')
+		for i := 0; decoder.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err = copyJSONValue(decoder, buf, fieldMap)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(']')
+
+	default:
+		return writeJSONScalar(buf, token)
+	}
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, v any) error {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(jsonBytes)
+	return nil
+}
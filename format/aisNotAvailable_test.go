@@ -0,0 +1,92 @@
+package format
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/BertoldVdb/go-ais"
+)
+
+func TestNullJSONFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		fields []string
+		want   string
+	}{
+		{"no fields", `{"a":1}`, nil, `{"a":1}`},
+		{"top-level field", `{"latitude":91,"longitude":103.8}`, []string{"latitude"}, `{"latitude":null,"longitude":103.8}`},
+		{"several fields", `{"sog":102.3,"cog":360,"trueHeading":511}`, []string{"sog", "trueHeading"}, `{"sog":null,"cog":360,"trueHeading":null}`},
+		{"object value", `{"dimension":{"a":1,"b":2},"x":true}`, []string{"dimension"}, `{"dimension":null,"x":true}`},
+		{"nested field", `{"a":{"latitude":91},"b":[{"latitude":91}]}`, []string{"latitude"}, `{"a":{"latitude":null},"b":[{"latitude":null}]}`},
+		{"missing field", `{"a":1}`, []string{"b"}, `{"a":1}`},
+		{"numbers kept as is", `{"a":1.50,"b":-0,"c":1e3}`, []string{"x"}, `{"a":1.50,"b":-0,"c":1e3}`},
+		{"strings and null", `{"s":"x\"y","n":null,"f":false}`, []string{"x"}, `{"s":"x\"y","n":null,"f":false}`},
+		{"empty containers", `{"o":{},"l":[]}`, []string{"x"}, `{"o":{},"l":[]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NullJSONFields([]byte(test.data), test.fields)
+			if err != nil {
+				t.Fatalf("NullJSONFields() error = %v", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("NullJSONFields() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNullJSONFieldsInvalid(t *testing.T) {
+	_, err := NullJSONFields([]byte(`{"a":`), []string{"a"})
+	if err == nil {
+		t.Errorf("NullJSONFields() error = nil, want error")
+	}
+}
+
+func TestAISNotAvailableFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet ais.Packet
+		want   []string
+	}{
+		{
+			"all available",
+			ais.PositionReport{Latitude: 1.25, Longitude: 103.8, Sog: 10, Cog: 45, TrueHeading: 45},
+			nil,
+		},
+		{
+			"position report sentinels",
+			ais.PositionReport{Latitude: 91, Longitude: 181, Sog: 102.3, Cog: 360, TrueHeading: 511, RateOfTurn: -128},
+			[]string{"Latitude", "Longitude", "Sog", "Cog", "TrueHeading", "RateOfTurn"},
+		},
+		{
+			"speed of 102.2 knots or more",
+			ais.StandardClassBPositionReport{Latitude: 1, Longitude: 1, Sog: 102.2, Cog: 359.9, TrueHeading: 359},
+			nil,
+		},
+		{
+			"search and rescue aircraft",
+			ais.StandardSearchAndRescueAircraftReport{Latitude: 1, Longitude: 1, Sog: 1023, Cog: 360, Altitude: 4095},
+			[]string{"Sog", "Cog", "Altitude"},
+		},
+		{
+			"long range broadcast",
+			ais.LongRangeAisBroadcastMessage{Latitude: 91, Longitude: 181, Sog: 63, Cog: 511},
+			[]string{"Latitude", "Longitude", "Sog", "Cog"},
+		},
+		{
+			"type without sentinels",
+			ais.ShipStaticData{},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := AISNotAvailableFields(test.packet)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("AISNotAvailableFields() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package format
+
+import (
+	"github.com/BertoldVdb/go-ais"
+)
+
+// AISPosition returns the position reported by an AIS packet. ok is false if the packet does not carry a position, or
+// if the position is not available.
+func AISPosition(packet ais.Packet) (latitude float64, longitude float64, ok bool) {
+	switch p := packet.(type) {
+	case ais.PositionReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.BaseStationReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.StandardSearchAndRescueAircraftReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.StandardClassBPositionReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.ExtendedClassBPositionReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.AidsToNavigationReport:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	case ais.LongRangeAisBroadcastMessage:
+		latitude, longitude = float64(p.Latitude), float64(p.Longitude)
+	default:
+		return 0, 0, false
+	}
+	return latitude, longitude, isValidPosition(latitude, longitude)
+}
@@ -1,8 +1,26 @@
 package format
 
-import "github.com/BertoldVdb/go-ais/aisnmea"
+import (
+	"github.com/BertoldVdb/go-ais"
+	"github.com/BertoldVdb/go-ais/aisnmea"
+)
 
 type AISRecord struct {
 	Timestamp int64              `json:"timestamp"`
 	AIS       *aisnmea.VdmPacket `json:"ais"`
+	Sources   []string           `json:"sources,omitempty"`
+}
+
+// Header returns the header of the message. The header of a message whose payload could not be decoded is read from
+// the payload.
+func (record *AISRecord) Header() *ais.Header {
+	if record.AIS.Packet != nil {
+		return record.AIS.Packet.GetHeader()
+	}
+	messageType, repeatIndicator, mmsi := aisPayloadHeader(record.AIS.Payload)
+	return &ais.Header{
+		MessageID:       messageType,
+		RepeatIndicator: repeatIndicator,
+		UserID:          mmsi,
+	}
 }
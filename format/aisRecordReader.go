@@ -1,6 +1,9 @@
 package format
 
 import (
+	"slices"
+	"time"
+
 	"github.com/BertoldVdb/go-ais"
 	"github.com/BertoldVdb/go-ais/aisnmea"
 )
@@ -8,23 +11,104 @@ import (
 type AISRecordReader struct {
 	loggerRecordReader *LoggerRecordReader
 	ignoreParseErrors  bool
-	aisCodec           *ais.Codec
 	nmeaCodec          *aisnmea.NMEACodec
+
+	keepUndecodable bool
+	undecodable     int
+
+	dedupeWindow    time.Duration
+	dedupeQueue     []*AISRecord
+	dedupeMap       map[string]*AISRecord
+	latestTimestamp int64
+	eof             bool
 }
 
 func NewAISRecordReader(loggerRecordReader *LoggerRecordReader, ignoreParseErrors bool) *AISRecordReader {
-	aisCodec := ais.CodecNew(false, false)
-	aisCodec.DropSpace = true
-	nmeaCodec := aisnmea.NMEACodecNew(aisCodec)
+	nmeaCodec := NewAISDecoder()
 	return &AISRecordReader{
 		loggerRecordReader: loggerRecordReader,
 		ignoreParseErrors:  ignoreParseErrors,
-		aisCodec:           aisCodec,
 		nmeaCodec:          nmeaCodec,
 	}
 }
 
+// NewAISDecoder returns a codec that decodes (and reassembles) AIS sentences the same way as AISRecordReader.
+func NewAISDecoder() *aisnmea.NMEACodec {
+	aisCodec := ais.CodecNew(false, false)
+	aisCodec.DropSpace = true
+	return aisnmea.NMEACodecNew(aisCodec)
+}
+
+// SetDedupeWindow enables the removal of duplicate messages, such as the same message heard by several receivers.
+// A message is dropped if an identical payload was read within the window before it; the sources of dropped messages
+// are added to the earliest record. Records are delayed by up to the window length. A window of 0 disables removal.
+func (reader *AISRecordReader) SetDedupeWindow(window time.Duration) {
+	reader.dedupeWindow = window
+	reader.dedupeMap = make(map[string]*AISRecord)
+}
+
+// SetKeepUndecodable sets whether messages whose payload cannot be decoded are returned, as records with a nil Packet,
+// rather than skipped.
+func (reader *AISRecordReader) SetKeepUndecodable(keepUndecodable bool) {
+	reader.keepUndecodable = keepUndecodable
+}
+
+// Undecodable returns the number of messages skipped so far as their payload could not be decoded.
+func (reader *AISRecordReader) Undecodable() int {
+	return reader.undecodable
+}
+
+// ReadAISRecord returns the next decoded AIS record, or nil at the end of the input. Unless SetKeepUndecodable is set,
+// messages whose payload cannot be decoded are skipped, so the returned records always carry a packet.
 func (reader *AISRecordReader) ReadAISRecord() (*AISRecord, error) {
+	if reader.dedupeWindow <= 0 {
+		return reader.readAISRecord()
+	}
+
+	window := reader.dedupeWindow.Milliseconds()
+	for {
+		if len(reader.dedupeQueue) > 0 {
+			head := reader.dedupeQueue[0]
+			if reader.eof || (head.Timestamp+window < reader.latestTimestamp) {
+				reader.dedupeQueue[0] = nil
+				reader.dedupeQueue = reader.dedupeQueue[1:]
+				key := string(head.AIS.Payload)
+				if reader.dedupeMap[key] == head {
+					delete(reader.dedupeMap, key)
+				}
+				return head, nil
+			}
+		}
+		if reader.eof {
+			return nil, nil
+		}
+
+		aisRecord, err := reader.readAISRecord()
+		if err != nil {
+			return nil, err
+		}
+		if aisRecord == nil {
+			reader.eof = true
+			continue
+		}
+		reader.latestTimestamp = max(reader.latestTimestamp, aisRecord.Timestamp)
+
+		key := string(aisRecord.AIS.Payload)
+		existing := reader.dedupeMap[key]
+		if (existing != nil) && (aisRecord.Timestamp-existing.Timestamp <= window) {
+			for _, source := range aisRecord.Sources {
+				if !slices.Contains(existing.Sources, source) {
+					existing.Sources = append(existing.Sources, source)
+				}
+			}
+			continue
+		}
+		reader.dedupeMap[key] = aisRecord
+		reader.dedupeQueue = append(reader.dedupeQueue, aisRecord)
+	}
+}
+
+func (reader *AISRecordReader) readAISRecord() (*AISRecord, error) {
 	for {
 		loggerRecord, err := reader.loggerRecordReader.ReadLoggerRecord()
 		if err != nil {
@@ -41,11 +125,22 @@ func (reader *AISRecordReader) ReadAISRecord() (*AISRecord, error) {
 			}
 		}
 
+		if (decoded != nil) && (decoded.Packet == nil) && !reader.keepUndecodable {
+			reader.undecodable++
+			continue
+		}
 		if decoded != nil {
 			aisRecord := &AISRecord{
 				Timestamp: loggerRecord.Timestamp,
 				AIS:       decoded,
 			}
+			source := loggerRecord.Source
+			if source == "" {
+				source = decoded.TagBlock.Source
+			}
+			if source != "" {
+				aisRecord.Sources = []string{source}
+			}
 			return aisRecord, nil
 		}
 	}
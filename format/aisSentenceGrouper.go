@@ -0,0 +1,62 @@
+package format
+
+import (
+	"github.com/BertoldVdb/go-ais/aisnmea"
+)
+
+// AISSentenceGroup holds the logger records that make up a single (possibly multipart) AIS message, together with the
+// decoded message.
+type AISSentenceGroup struct {
+	Records []*LoggerRecord
+	VDM     *aisnmea.VdmPacket
+}
+
+// AISSentenceGrouper reassembles multipart AIS messages while keeping track of the logger records they came from.
+type AISSentenceGrouper struct {
+	decoder     *aisnmea.NMEACodec
+	pending     map[string][]*LoggerRecord
+	lastChannel string
+}
+
+func NewAISSentenceGrouper() *AISSentenceGrouper {
+	return &AISSentenceGrouper{
+		decoder:     NewAISDecoder(),
+		pending:     make(map[string][]*LoggerRecord),
+		lastChannel: "A",
+	}
+}
+
+// Add adds an encapsulated AIS sentence. A group is returned once all the parts of a message have been added.
+func (grouper *AISSentenceGrouper) Add(record *LoggerRecord, sentenceInfo *SentenceInfo) (*AISSentenceGroup, error) {
+	var key string
+	if len(sentenceInfo.Fields) >= 4 {
+		if sentenceInfo.Fields[3] != "" {
+			grouper.lastChannel = sentenceInfo.Fields[3]
+		}
+		if sentenceInfo.Fields[0] != "1" {
+			key = sentenceInfo.Prefix() + "," + sentenceInfo.Fields[0] + "," + sentenceInfo.Fields[2] + "," + grouper.lastChannel
+			if sentenceInfo.Fields[1] == "1" {
+				delete(grouper.pending, key)
+			}
+			grouper.pending[key] = append(grouper.pending[key], record)
+		}
+	}
+
+	vdm, err := grouper.decoder.ParseSentence(record.NMEA)
+	if err != nil {
+		return nil, err
+	}
+	if vdm == nil {
+		return nil, nil
+	}
+
+	records := []*LoggerRecord{record}
+	if key != "" {
+		records = grouper.pending[key]
+		delete(grouper.pending, key)
+	}
+	return &AISSentenceGroup{
+		Records: records,
+		VDM:     vdm,
+	}, nil
+}
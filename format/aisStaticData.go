@@ -0,0 +1,73 @@
+package format
+
+import (
+	"github.com/BertoldVdb/go-ais"
+)
+
+// aisStaticData accumulates the static data of a vessel. Class A vessels report it in type 5 messages, together with
+// their voyage data. Class B vessels report it in type 19 messages, or split across type 24 parts A and B.
+type aisStaticData struct {
+	UserID      uint32
+	Class       string
+	ImoNumber   uint32
+	Name        string
+	CallSign    string
+	Type        uint8
+	Dimension   ais.FieldDimension
+	HasVoyage   bool
+	Draught     float64
+	Destination string
+	Eta         ais.FieldETA
+
+	hasName     bool
+	hasShipData bool
+}
+
+// update merges the static data carried by packet and reports whether packet carried any.
+func (data *aisStaticData) update(packet ais.Packet) bool {
+	switch report := packet.(type) {
+	case ais.ShipStaticData:
+		data.Class = "A"
+		data.Name = report.Name
+		data.hasName = true
+		data.ImoNumber = report.ImoNumber
+		data.CallSign = report.CallSign
+		data.Type = report.Type
+		data.Dimension = report.Dimension
+		data.hasShipData = true
+		data.Draught = float64(report.MaximumStaticDraught)
+		data.Destination = report.Destination
+		data.Eta = report.Eta
+		data.HasVoyage = true
+
+	case ais.ExtendedClassBPositionReport:
+		data.Class = "B"
+		data.Name = report.Name
+		data.hasName = true
+		data.Type = report.Type
+		data.Dimension = report.Dimension
+		data.hasShipData = true
+
+	case ais.StaticDataReport:
+		data.Class = "B"
+		if report.ReportA.Valid {
+			data.Name = report.ReportA.Name
+			data.hasName = true
+		}
+		if report.ReportB.Valid {
+			data.CallSign = report.ReportB.CallSign
+			data.Type = report.ReportB.ShipType
+			data.Dimension = report.ReportB.Dimension
+			data.hasShipData = true
+		}
+
+	default:
+		return false
+	}
+	return true
+}
+
+// complete reports whether both the name and the ship data have been reported. data may be nil.
+func (data *aisStaticData) complete() bool {
+	return (data != nil) && data.hasName && data.hasShipData
+}
@@ -0,0 +1,78 @@
+package format
+
+import (
+	"sort"
+
+	"github.com/BertoldVdb/go-ais"
+)
+
+type aisTrackPoint struct {
+	Timestamp int64
+	Latitude  float64
+	Longitude float64
+}
+
+type aisTrack struct {
+	UserID         uint32
+	Points         []aisTrackPoint
+	ShipStaticData *ais.ShipStaticData
+}
+
+// aisTrackCollector groups position reports into per-vessel tracks.
+type aisTrackCollector struct {
+	trackMap map[uint32]*aisTrack
+}
+
+func newAISTrackCollector() *aisTrackCollector {
+	return &aisTrackCollector{
+		trackMap: make(map[uint32]*aisTrack),
+	}
+}
+
+func (collector *aisTrackCollector) getTrack(userID uint32) *aisTrack {
+	track, ok := collector.trackMap[userID]
+	if !ok {
+		track = &aisTrack{
+			UserID: userID,
+		}
+		collector.trackMap[userID] = track
+	}
+	return track
+}
+
+func (collector *aisTrackCollector) add(record *AISRecord) {
+	switch report := record.AIS.Packet.(type) {
+	case ais.PositionReport:
+		if !isValidPosition(float64(report.Latitude), float64(report.Longitude)) {
+			return
+		}
+		track := collector.getTrack(report.UserID)
+		track.Points = append(track.Points, aisTrackPoint{
+			Timestamp: record.Timestamp,
+			Latitude:  float64(report.Latitude),
+			Longitude: float64(report.Longitude),
+		})
+
+	case ais.ShipStaticData:
+		track := collector.getTrack(report.UserID)
+		track.ShipStaticData = &report
+	}
+}
+
+// tracks returns the tracks that contain at least one point, ordered by MMSI.
+func (collector *aisTrackCollector) tracks() []*aisTrack {
+	var tracks []*aisTrack
+	for _, track := range collector.trackMap {
+		if len(track.Points) > 0 {
+			tracks = append(tracks, track)
+		}
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].UserID < tracks[j].UserID
+	})
+	return tracks
+}
+
+func isValidPosition(latitude float64, longitude float64) bool {
+	return (latitude >= -90) && (latitude <= 90) && (longitude >= -180) && (longitude <= 180)
+}
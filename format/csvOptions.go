@@ -0,0 +1,364 @@
+package format
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/aiscode"
+)
+
+// CsvOptions controls the layout of CSV output.
+type CsvOptions struct {
+	// Columns lists the columns of position rows by name (see CsvColumnNames). If empty, the writer's default columns
+	// are written.
+	Columns []string
+	// Location is the time zone in which date times are rendered.
+	Location *time.Location
+	// DateTimeLayout is the Go time layout of date times.
+	DateTimeLayout string
+	// EtaLayout is the Go time layout of the ETA column. ETAs that are not valid dates are rendered as MM-DD hh:mm.
+	EtaLayout string
+	// CoordinatePrecision is the number of decimal places of latitudes and longitudes (-1 for full precision).
+	CoordinatePrecision int
+	// Delimiter is the field delimiter.
+	Delimiter rune
+	// StaticDataLookahead is how long a position row is held back while waiting for the vessel's static data.
+	StaticDataLookahead time.Duration
+	// Enrich appends the flag state, station class and code description columns to the default columns.
+	Enrich bool
+	// Raw writes AIS "not available" values (e.g. latitude 91 or heading 511) as is, instead of as empty cells.
+	Raw bool
+}
+
+func DefaultCsvOptions() CsvOptions {
+	return CsvOptions{
+		Location:            time.UTC,
+		DateTimeLayout:      "2006-01-02 15:04:05",
+		EtaLayout:           "01-02 15:04",
+		CoordinatePrecision: 5,
+		Delimiter:           ',',
+		StaticDataLookahead: 6 * time.Minute,
+	}
+}
+
+// ParseCsvDelimiter parses a delimiter given on the command line. "tab" and "\t" are accepted for the tab character.
+func ParseCsvDelimiter(s string) (rune, error) {
+	switch s {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	runes := []rune(s)
+	if (len(runes) != 1) || (runes[0] == '"') || (runes[0] == '\r') || (runes[0] == '\n') {
+		return 0, fmt.Errorf("invalid delimiter")
+	}
+	return runes[0], nil
+}
+
+func (options *CsvOptions) newWriter(w io.Writer) *csv.Writer {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = options.Delimiter
+	return csvWriter
+}
+
+func (options *CsvOptions) location() *time.Location {
+	if options.Location == nil {
+		return time.UTC
+	}
+	return options.Location
+}
+
+// dateTimeHeader returns a date time column header, qualified by the time zone.
+func (options *CsvOptions) dateTimeHeader(name string) string {
+	return fmt.Sprintf("%s (%s)", name, options.location())
+}
+
+func (options *CsvOptions) formatDateTime(timestamp int64) string {
+	return time.UnixMilli(timestamp).In(options.location()).Format(options.DateTimeLayout)
+}
+
+func (options *CsvOptions) formatCoordinate(v float64) string {
+	if options.CoordinatePrecision >= 0 {
+		v = roundToDecimalPoints(v, options.CoordinatePrecision)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatLatitude formats a latitude, or returns "" if it is "not available".
+func (options *CsvOptions) formatLatitude(latitude float64) string {
+	if !options.Raw && !isLatitudeAvailable(latitude) {
+		return ""
+	}
+	return options.formatCoordinate(latitude)
+}
+
+// formatLongitude formats a longitude, or returns "" if it is "not available".
+func (options *CsvOptions) formatLongitude(longitude float64) string {
+	if !options.Raw && !isLongitudeAvailable(longitude) {
+		return ""
+	}
+	return options.formatCoordinate(longitude)
+}
+
+func (options *CsvOptions) formatEta(month uint8, day uint8, hour uint8, minute uint8) string {
+	raw := fmt.Sprintf("%02d-%02d %02d:%02d", month, day, hour, minute)
+	if (month < 1) || (month > 12) || (day < 1) || (day > 31) || (hour > 23) || (minute > 59) {
+		return raw
+	}
+	// Year 0 is a leap year, so February 29 is kept. Days beyond the end of the month (e.g. 02-30) would be normalized
+	// into the next month, so they are written as is.
+	t := time.Date(0, time.Month(month), int(day), int(hour), int(minute), 0, 0, time.UTC)
+	if (t.Month() != time.Month(month)) || (t.Day() != int(day)) {
+		return raw
+	}
+	return t.Format(options.EtaLayout)
+}
+
+// csvRow is the data from which a position row is rendered. Position is nil for rows that only carry static data.
+type csvRow struct {
+	Timestamp  int64
+	UserID     uint32
+	Position   *aisVesselPosition
+	StaticData *aisStaticData
+}
+
+type csvColumn struct {
+	header func(options *CsvOptions) string
+	value  func(options *CsvOptions, row *csvRow) string
+}
+
+func fixedCsvHeader(header string) func(options *CsvOptions) string {
+	return func(options *CsvOptions) string {
+		return header
+	}
+}
+
+func positionCsvColumn(header string, value func(options *CsvOptions, position *aisVesselPosition) string) csvColumn {
+	return csvColumn{
+		header: fixedCsvHeader(header),
+		value: func(options *CsvOptions, row *csvRow) string {
+			if row.Position == nil {
+				return ""
+			}
+			return value(options, row.Position)
+		},
+	}
+}
+
+// staticDataCsvColumn returns a column that is empty unless the part of the static data it is taken from has been
+// reported.
+func staticDataCsvColumn(header string, reported func(staticData *aisStaticData) bool, value func(options *CsvOptions, staticData *aisStaticData) string) csvColumn {
+	return csvColumn{
+		header: fixedCsvHeader(header),
+		value: func(options *CsvOptions, row *csvRow) string {
+			if (row.StaticData == nil) || !reported(row.StaticData) {
+				return ""
+			}
+			return value(options, row.StaticData)
+		},
+	}
+}
+
+func staticDataHasName(staticData *aisStaticData) bool {
+	return staticData.hasName
+}
+
+func staticDataHasShipData(staticData *aisStaticData) bool {
+	return staticData.hasShipData
+}
+
+func staticDataHasVoyage(staticData *aisStaticData) bool {
+	return staticData.HasVoyage
+}
+
+// CsvColumnNames lists the names of the columns available to position rows.
+var CsvColumnNames = []string{
+	"datetime", "epoch", "mmsi", "class",
+	"latitude", "longitude", "course", "speed", "heading", "navstat",
+	"imo", "name", "callsign", "aistype", "a", "b", "c", "d", "draught", "destination", "eta",
+	"flag", "country", "stationclass", "navstattext", "aistypetext",
+}
+
+var csvColumns = map[string]csvColumn{
+	"datetime": {
+		header: func(options *CsvOptions) string {
+			return options.dateTimeHeader("DATE TIME")
+		},
+		value: func(options *CsvOptions, row *csvRow) string {
+			return options.formatDateTime(row.Timestamp)
+		},
+	},
+	"epoch": {
+		header: fixedCsvHeader("EPOCH TIME"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			return strconv.FormatInt(row.Timestamp/1000, 10)
+		},
+	},
+	"mmsi": {
+		header: fixedCsvHeader("MMSI"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			return strconv.FormatInt(int64(row.UserID), 10)
+		},
+	},
+	"class": {
+		header: fixedCsvHeader("CLASS"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			if row.Position != nil {
+				return row.Position.Class
+			}
+			if row.StaticData != nil {
+				return row.StaticData.Class
+			}
+			return ""
+		},
+	},
+	"latitude": positionCsvColumn("LATITUDE", func(options *CsvOptions, position *aisVesselPosition) string {
+		return options.formatLatitude(position.Latitude)
+	}),
+	"longitude": positionCsvColumn("LONGITUDE", func(options *CsvOptions, position *aisVesselPosition) string {
+		return options.formatLongitude(position.Longitude)
+	}),
+	"course": positionCsvColumn("COURSE", func(options *CsvOptions, position *aisVesselPosition) string {
+		if !options.Raw && !isCogAvailable(position.Cog) {
+			return ""
+		}
+		return strconv.FormatFloat(position.Cog, 'f', -1, 64)
+	}),
+	"speed": positionCsvColumn("SPEED", func(options *CsvOptions, position *aisVesselPosition) string {
+		if !options.Raw && !isSogAvailable(position.Sog) {
+			return ""
+		}
+		return strconv.FormatFloat(position.Sog, 'f', -1, 64)
+	}),
+	"heading": positionCsvColumn("HEADING", func(options *CsvOptions, position *aisVesselPosition) string {
+		if !options.Raw && !isHeadingAvailable(position.TrueHeading) {
+			return ""
+		}
+		return strconv.FormatInt(int64(position.TrueHeading), 10)
+	}),
+	"navstat": positionCsvColumn("NAVSTAT", func(options *CsvOptions, position *aisVesselPosition) string {
+		if position.NavigationalStatus == nil {
+			return ""
+		}
+		return strconv.FormatInt(int64(*position.NavigationalStatus), 10)
+	}),
+	"imo": staticDataCsvColumn("IMO", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		if staticData.Class != "A" {
+			return ""
+		}
+		return strconv.FormatInt(int64(staticData.ImoNumber), 10)
+	}),
+	"name": staticDataCsvColumn("NAME", staticDataHasName, func(options *CsvOptions, staticData *aisStaticData) string {
+		return staticData.Name
+	}),
+	"callsign": staticDataCsvColumn("CALLSIGN", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return staticData.CallSign
+	}),
+	"aistype": staticDataCsvColumn("AISTYPE", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatInt(int64(staticData.Type), 10)
+	}),
+	"a": staticDataCsvColumn("A", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatInt(int64(staticData.Dimension.A), 10)
+	}),
+	"b": staticDataCsvColumn("B", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatInt(int64(staticData.Dimension.B), 10)
+	}),
+	"c": staticDataCsvColumn("C", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatInt(int64(staticData.Dimension.C), 10)
+	}),
+	"d": staticDataCsvColumn("D", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatInt(int64(staticData.Dimension.D), 10)
+	}),
+	"draught": staticDataCsvColumn("DRAUGHT", staticDataHasVoyage, func(options *CsvOptions, staticData *aisStaticData) string {
+		return strconv.FormatFloat(staticData.Draught, 'f', -1, 64)
+	}),
+	"destination": staticDataCsvColumn("DESTINATION", staticDataHasVoyage, func(options *CsvOptions, staticData *aisStaticData) string {
+		return staticData.Destination
+	}),
+	"eta": staticDataCsvColumn("ETA", staticDataHasVoyage, func(options *CsvOptions, staticData *aisStaticData) string {
+		return options.formatEta(staticData.Eta.Month, staticData.Eta.Day, staticData.Eta.Hour, staticData.Eta.Minute)
+	}),
+	"flag": {
+		header: fixedCsvHeader("FLAG"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			country, _ := aiscode.Flag(row.UserID)
+			return country.Code
+		},
+	},
+	"country": {
+		header: fixedCsvHeader("COUNTRY"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			country, _ := aiscode.Flag(row.UserID)
+			return country.Name
+		},
+	},
+	"stationclass": {
+		header: fixedCsvHeader("STATIONCLASS"),
+		value: func(options *CsvOptions, row *csvRow) string {
+			stationClass, _ := aiscode.Classify(row.UserID)
+			return string(stationClass)
+		},
+	},
+	"navstattext": positionCsvColumn("NAVSTAT TEXT", func(options *CsvOptions, position *aisVesselPosition) string {
+		if position.NavigationalStatus == nil {
+			return ""
+		}
+		return aiscode.NavigationalStatusText(*position.NavigationalStatus)
+	}),
+	"aistypetext": staticDataCsvColumn("AISTYPE TEXT", staticDataHasShipData, func(options *CsvOptions, staticData *aisStaticData) string {
+		return aiscode.ShipTypeText(staticData.Type)
+	}),
+}
+
+// csvEnrichmentColumns are appended to the default columns if CsvOptions.Enrich is set.
+var csvEnrichmentColumns = []string{"flag", "country", "stationclass", "navstattext", "aistypetext"}
+
+// ValidateCsvColumns checks that every name in columns is one of CsvColumnNames.
+func ValidateCsvColumns(columns []string) error {
+	for _, column := range columns {
+		_, ok := csvColumns[strings.ToLower(column)]
+		if !ok {
+			return fmt.Errorf("unknown column: %s (available: %s)", column, strings.Join(CsvColumnNames, ", "))
+		}
+	}
+	return nil
+}
+
+// columnsOf returns the named columns, or the default columns if names is empty.
+func (options *CsvOptions) columnsOf(names []string, defaultColumns []string) ([]csvColumn, error) {
+	if len(names) == 0 {
+		names = defaultColumns
+		if options.Enrich {
+			names = append(slices.Clone(names), csvEnrichmentColumns...)
+		}
+	}
+	err := ValidateCsvColumns(names)
+	if err != nil {
+		return nil, err
+	}
+	var columns []csvColumn
+	for _, name := range names {
+		columns = append(columns, csvColumns[strings.ToLower(name)])
+	}
+	return columns, nil
+}
+
+func (options *CsvOptions) header(columns []csvColumn) []string {
+	var cells []string
+	for _, column := range columns {
+		cells = append(cells, column.header(options))
+	}
+	return cells
+}
+
+func (options *CsvOptions) row(columns []csvColumn, row *csvRow) []string {
+	var cells []string
+	for _, column := range columns {
+		cells = append(cells, column.value(options, row))
+	}
+	return cells
+}
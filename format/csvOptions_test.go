@@ -0,0 +1,28 @@
+package format
+
+import "testing"
+
+func TestFormatEta(t *testing.T) {
+	options := DefaultCsvOptions()
+	options.EtaLayout = "Jan 2 15:04"
+	tests := []struct {
+		name                     string
+		month, day, hour, minute uint8
+		want                     string
+	}{
+		{"valid", 3, 1, 12, 30, "Mar 1 12:30"},
+		{"leap day", 2, 29, 0, 0, "Feb 29 00:00"},
+		{"not available", 0, 0, 24, 60, "00-00 24:60"},
+		{"day beyond end of month", 2, 30, 8, 0, "02-30 08:00"},
+		{"day 31 of a 30-day month", 4, 31, 8, 0, "04-31 08:00"},
+		{"hour not available", 6, 15, 24, 0, "06-15 24:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := options.formatEta(test.month, test.day, test.hour, test.minute)
+			if got != test.want {
+				t.Errorf("formatEta(%d, %d, %d, %d) = %q, want %q", test.month, test.day, test.hour, test.minute, got, test.want)
+			}
+		})
+	}
+}
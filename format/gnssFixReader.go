@@ -0,0 +1,165 @@
+package format
+
+import (
+	"time"
+)
+
+// GNSSFix is an own-ship position fix assembled from the RMC and GGA sentences of a single epoch. Source is the source
+// of the sentences; sentences of different sources are never merged.
+type GNSSFix struct {
+	Timestamp     int64
+	Source        string
+	Time          time.Time
+	Valid         bool
+	Latitude      float64
+	Longitude     float64
+	Altitude      *float64
+	Speed         *float64
+	Course        *float64
+	FixQuality    string
+	NumSatellites *int64
+	HDOP          *float64
+
+	hasDate bool
+}
+
+type GNSSFixReader struct {
+	nmeaRecordReader *NMEARecordReader
+	pending          *GNSSFix
+	pendingTimeOfDay string
+	lastTime         time.Time
+	lastTimestamp    int64
+}
+
+func NewGNSSFixReader(nmeaRecordReader *NMEARecordReader) *GNSSFixReader {
+	return &GNSSFixReader{
+		nmeaRecordReader: nmeaRecordReader,
+	}
+}
+
+func (reader *GNSSFixReader) ReadGNSSFix() (*GNSSFix, error) {
+	for {
+		nmeaRecord, err := reader.nmeaRecordReader.ReadNMEARecord()
+		if err != nil {
+			return nil, err
+		}
+		if nmeaRecord == nil {
+			fix := reader.pending
+			reader.pending = nil
+			return fix, nil
+		}
+
+		var timeOfDay string
+		var fix *GNSSFix
+		switch data := nmeaRecord.Data.(type) {
+		case *RMCData:
+			if (data.Time == "") || (data.Date == "") {
+				continue
+			}
+			t, err := time.Parse("2006-01-02 15:04:05.000", data.Date+" "+data.Time)
+			if err != nil {
+				continue
+			}
+			timeOfDay = data.Time
+			fix = &GNSSFix{
+				hasDate:   true,
+				Timestamp: nmeaRecord.Timestamp,
+				Source:    nmeaRecord.Source,
+				Time:      t,
+				Valid:     data.Validity == "A",
+				Latitude:  data.Latitude,
+				Longitude: data.Longitude,
+				Speed:     &data.Speed,
+				Course:    &data.Course,
+			}
+
+		case *GGAData:
+			if data.Time == "" {
+				continue
+			}
+			t, err := reader.resolveTimeOfDay(data.Time, nmeaRecord.Timestamp)
+			if err != nil {
+				continue
+			}
+			timeOfDay = data.Time
+			fix = &GNSSFix{
+				Timestamp:     nmeaRecord.Timestamp,
+				Source:        nmeaRecord.Source,
+				Time:          t,
+				Valid:         (data.FixQuality != "") && (data.FixQuality != "0"),
+				Latitude:      data.Latitude,
+				Longitude:     data.Longitude,
+				Altitude:      &data.Altitude,
+				FixQuality:    data.FixQuality,
+				NumSatellites: &data.NumSatellites,
+				HDOP:          &data.HDOP,
+			}
+
+		default:
+			continue
+		}
+
+		reader.lastTime = fix.Time
+		reader.lastTimestamp = fix.Timestamp
+
+		if (reader.pending != nil) && (reader.pending.Source == fix.Source) && (reader.pendingTimeOfDay == timeOfDay) {
+			mergeGNSSFix(reader.pending, fix)
+			continue
+		}
+		previous := reader.pending
+		reader.pending = fix
+		reader.pendingTimeOfDay = timeOfDay
+		if previous != nil {
+			return previous, nil
+		}
+	}
+}
+
+// resolveTimeOfDay attaches a date to a time of day. The date is taken from the previous fix, extrapolated by the
+// elapsed logger time, or from the logger timestamp if there is no previous fix.
+func (reader *GNSSFixReader) resolveTimeOfDay(timeOfDay string, timestamp int64) (time.Time, error) {
+	tod, err := time.Parse("15:04:05.000", timeOfDay)
+	if err != nil {
+		return time.Time{}, err
+	}
+	offset := time.Duration(tod.Hour())*time.Hour + time.Duration(tod.Minute())*time.Minute +
+		time.Duration(tod.Second())*time.Second + time.Duration(tod.Nanosecond())
+
+	reference := time.UnixMilli(timestamp).UTC()
+	if !reader.lastTime.IsZero() {
+		reference = reader.lastTime.Add(time.Duration(timestamp-reader.lastTimestamp) * time.Millisecond)
+	}
+	t := reference.Truncate(24 * time.Hour).Add(offset)
+	if d := t.Sub(reference); d > 12*time.Hour {
+		t = t.Add(-24 * time.Hour)
+	} else if d < -12*time.Hour {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, nil
+}
+
+func mergeGNSSFix(fix *GNSSFix, other *GNSSFix) {
+	fix.Valid = fix.Valid && other.Valid
+	if other.hasDate {
+		fix.Time = other.Time
+		fix.hasDate = true
+	}
+	if other.Altitude != nil {
+		fix.Altitude = other.Altitude
+	}
+	if other.Speed != nil {
+		fix.Speed = other.Speed
+	}
+	if other.Course != nil {
+		fix.Course = other.Course
+	}
+	if other.FixQuality != "" {
+		fix.FixQuality = other.FixQuality
+	}
+	if other.NumSatellites != nil {
+		fix.NumSatellites = other.NumSatellites
+	}
+	if other.HDOP != nil {
+		fix.HDOP = other.HDOP
+	}
+}
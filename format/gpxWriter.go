@@ -0,0 +1,132 @@
+package format
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type GpxTrackPoint struct {
+	XMLName    xml.Name           `xml:"trkpt"`
+	Latitude   float64            `xml:"lat,attr"`
+	Longitude  float64            `xml:"lon,attr"`
+	Elevation  *float64           `xml:"ele,omitempty"`
+	Time       string             `xml:"time"`
+	Fix        string             `xml:"fix,omitempty"`
+	Sat        *int64             `xml:"sat,omitempty"`
+	HDOP       *float64           `xml:"hdop,omitempty"`
+	Extensions *GpxPointExtension `xml:"extensions,omitempty"`
+}
+
+// GpxPointExtension is a Garmin TrackPointExtension (v2), which carries speed (m/s) and course (degrees).
+type GpxPointExtension struct {
+	TrackPointExtension struct {
+		Speed  *float64 `xml:"gpxtpx:speed,omitempty"`
+		Course *float64 `xml:"gpxtpx:course,omitempty"`
+	} `xml:"gpxtpx:TrackPointExtension"`
+}
+
+// GpxWriter writes a GPX 1.1 document containing a single track. Points are streamed to the underlying writer.
+type GpxWriter struct {
+	w         io.Writer
+	encoder   *xml.Encoder
+	inSegment bool
+}
+
+func NewGpxWriter(w io.Writer, name string) (*GpxWriter, error) {
+	_, err := io.WriteString(w, xml.Header+
+		`<gpx version="1.1" creator="nmea-logger"`+
+		` xmlns="http://www.topografix.com/GPX/1/1"`+
+		` xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2"`+
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`+
+		` xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd`+
+		` http://www.garmin.com/xmlschemas/TrackPointExtension/v2 http://www.garmin.com/xmlschemas/TrackPointExtensionv2.xsd">`+
+		"\n<trk>\n")
+	if err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	if name != "" {
+		err = encoder.EncodeElement(name, xml.StartElement{Name: xml.Name{Local: "name"}})
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.Flush()
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &GpxWriter{
+		w:       w,
+		encoder: encoder,
+	}, nil
+}
+
+func (writer *GpxWriter) Close() error {
+	err := writer.EndSegment()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer.w, "</trk>\n</gpx>\n")
+	return err
+}
+
+// EndSegment ends the current track segment, if any. The next point starts a new segment.
+func (writer *GpxWriter) EndSegment() error {
+	if !writer.inSegment {
+		return nil
+	}
+	writer.inSegment = false
+	_, err := io.WriteString(writer.w, "</trkseg>\n")
+	return err
+}
+
+func (writer *GpxWriter) WriteGNSSFix(fix *GNSSFix) error {
+	if !writer.inSegment {
+		_, err := io.WriteString(writer.w, "<trkseg>\n")
+		if err != nil {
+			return err
+		}
+		writer.inSegment = true
+	}
+
+	point := GpxTrackPoint{
+		Latitude:  roundToDecimalPoints(fix.Latitude, 7),
+		Longitude: roundToDecimalPoints(fix.Longitude, 7),
+		Elevation: fix.Altitude,
+		Time:      fix.Time.UTC().Format(time.RFC3339Nano),
+		Sat:       fix.NumSatellites,
+		HDOP:      fix.HDOP,
+	}
+	switch fix.FixQuality {
+	case "1":
+		point.Fix = "3d"
+	case "2":
+		point.Fix = "dgps"
+	}
+	if (fix.Speed != nil) || (fix.Course != nil) {
+		point.Extensions = &GpxPointExtension{}
+		if fix.Speed != nil {
+			speed := roundToDecimalPoints(*fix.Speed*knotsToMetersPerSecond, 3)
+			point.Extensions.TrackPointExtension.Speed = &speed
+		}
+		point.Extensions.TrackPointExtension.Course = fix.Course
+	}
+
+	err := writer.encoder.Encode(point)
+	if err != nil {
+		return err
+	}
+	err = writer.encoder.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer.w, "\n")
+	return err
+}
+
+const knotsToMetersPerSecond = 1852.0 / 3600.0
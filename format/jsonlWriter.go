@@ -24,7 +24,12 @@ func (writer *JsonlWriter) WriteRecord(v any) error {
 	if err != nil {
 		return err
 	}
-	_, err = writer.w.Write(jsonBytes)
+	return writer.WriteLine(jsonBytes)
+}
+
+// WriteLine writes a line that is already encoded.
+func (writer *JsonlWriter) WriteLine(line []byte) error {
+	_, err := writer.w.Write(line)
 	if err != nil {
 		return err
 	}
@@ -3,4 +3,5 @@ package format
 type LoggerRecord struct {
 	Timestamp int64  `json:"timestamp"`
 	NMEA      string `json:"nmea"`
+	Source    string `json:"source,omitempty"`
 }
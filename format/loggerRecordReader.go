@@ -4,11 +4,29 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 )
 
+// MalformedRecordError is returned by LoggerRecordReader when a line cannot be decoded. Reading may continue with the
+// next line. Text is the content of the line.
+type MalformedRecordError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *MalformedRecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *MalformedRecordError) Unwrap() error {
+	return e.Err
+}
+
 type LoggerRecordReader struct {
 	scanner *bufio.Scanner
+	line    int
 }
 
 func NewLoggerRecordReader(r io.Reader) *LoggerRecordReader {
@@ -23,6 +41,7 @@ func (reader *LoggerRecordReader) ReadLoggerRecord() (*LoggerRecord, error) {
 		return nil, reader.scanner.Err()
 	}
 
+	reader.line++
 	logLineBytes := reader.scanner.Bytes()
 
 	jsonDecoder := json.NewDecoder(bytes.NewReader(logLineBytes))
@@ -31,8 +50,17 @@ func (reader *LoggerRecordReader) ReadLoggerRecord() (*LoggerRecord, error) {
 	var loggerRecord LoggerRecord
 	err := jsonDecoder.Decode(&loggerRecord)
 	if err != nil {
-		return nil, err
+		return nil, &MalformedRecordError{
+			Line: reader.line,
+			Text: string(logLineBytes),
+			Err:  err,
+		}
 	}
 
 	return &loggerRecord, nil
 }
+
+// Line returns the line number of the last line read.
+func (reader *LoggerRecordReader) Line() int {
+	return reader.line
+}
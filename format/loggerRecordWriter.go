@@ -0,0 +1,28 @@
+package format
+
+import (
+	"io"
+)
+
+type LoggerRecordWriter struct {
+	jsonlWriter *JsonlWriter
+}
+
+func NewLoggerRecordWriter(w io.Writer) *LoggerRecordWriter {
+	return &LoggerRecordWriter{
+		jsonlWriter: NewJsonlWriter(w),
+	}
+}
+
+func (writer *LoggerRecordWriter) Close() error {
+	return writer.jsonlWriter.Close()
+}
+
+func (writer *LoggerRecordWriter) WriteLoggerRecord(record *LoggerRecord) error {
+	return writer.jsonlWriter.WriteRecord(record)
+}
+
+// WriteLine writes a line as is, e.g. a malformed record that is passed through.
+func (writer *LoggerRecordWriter) WriteLine(line string) error {
+	return writer.jsonlWriter.WriteLine([]byte(line))
+}
@@ -0,0 +1,37 @@
+package format
+
+import (
+	nmea "github.com/adrianmo/go-nmea"
+)
+
+const (
+	// TypeMWV type for MWV sentences
+	TypeMWV = "MWV"
+)
+
+// MWV is the Wind Speed and Angle sentence, which is not supported by go-nmea.
+type MWV struct {
+	nmea.BaseSentence
+	WindAngle     float64 // Wind angle, 0 to 359 degrees
+	Reference     string  // R = relative, T = theoretical/true
+	WindSpeed     float64 // Wind speed
+	WindSpeedUnit string  // K = km/h, M = m/s, N = knots, S = statute miles/h
+	Status        string  // A = data valid, V = data invalid
+}
+
+func init() {
+	nmea.MustRegisterParser(TypeMWV, newMWV)
+}
+
+func newMWV(s nmea.BaseSentence) (nmea.Sentence, error) {
+	p := nmea.NewParser(s)
+	p.AssertType(TypeMWV)
+	return MWV{
+		BaseSentence:  s,
+		WindAngle:     p.Float64(0, "wind angle"),
+		Reference:     p.EnumString(1, "reference", "R", "T"),
+		WindSpeed:     p.Float64(2, "wind speed"),
+		WindSpeedUnit: p.EnumString(3, "wind speed unit", "K", "M", "N", "S"),
+		Status:        p.EnumString(4, "status", "A", "V"),
+	}, p.Err()
+}
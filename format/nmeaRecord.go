@@ -0,0 +1,217 @@
+package format
+
+import (
+	"fmt"
+
+	nmea "github.com/adrianmo/go-nmea"
+)
+
+type NMEARecord struct {
+	Timestamp int64  `json:"timestamp"`
+	Talker    string `json:"talker"`
+	Type      string `json:"type"`
+	Data      any    `json:"data"`
+	Source    string `json:"source,omitempty"`
+}
+
+type GGAData struct {
+	Time          string  `json:"time"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	FixQuality    string  `json:"fixQuality"`
+	NumSatellites int64   `json:"numSatellites"`
+	HDOP          float64 `json:"hdop"`
+	Altitude      float64 `json:"altitude"`
+	Separation    float64 `json:"separation"`
+	DGPSAge       string  `json:"dgpsAge,omitempty"`
+	DGPSId        string  `json:"dgpsId,omitempty"`
+}
+
+type GLLData struct {
+	Time      string  `json:"time"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Validity  string  `json:"validity"`
+}
+
+type RMCData struct {
+	Date      string  `json:"date"`
+	Time      string  `json:"time"`
+	Validity  string  `json:"validity"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Speed     float64 `json:"speed"`
+	Course    float64 `json:"course"`
+	Variation float64 `json:"variation"`
+}
+
+type VTGData struct {
+	TrueTrack        float64 `json:"trueTrack"`
+	MagneticTrack    float64 `json:"magneticTrack"`
+	GroundSpeedKnots float64 `json:"groundSpeedKnots"`
+	GroundSpeedKPH   float64 `json:"groundSpeedKph"`
+}
+
+type ZDAData struct {
+	Date          string `json:"date"`
+	Time          string `json:"time"`
+	OffsetHours   int64  `json:"offsetHours"`
+	OffsetMinutes int64  `json:"offsetMinutes"`
+}
+
+type HDTData struct {
+	Heading float64 `json:"heading"`
+	True    bool    `json:"true"`
+}
+
+type THSData struct {
+	Heading float64 `json:"heading"`
+	Status  string  `json:"status"`
+}
+
+type DBTData struct {
+	DepthFeet    float64 `json:"depthFeet"`
+	DepthMeters  float64 `json:"depthMeters"`
+	DepthFathoms float64 `json:"depthFathoms"`
+}
+
+type DPTData struct {
+	Depth      float64 `json:"depth"`
+	Offset     float64 `json:"offset"`
+	RangeScale float64 `json:"rangeScale"`
+}
+
+type VHWData struct {
+	TrueHeading            float64 `json:"trueHeading"`
+	MagneticHeading        float64 `json:"magneticHeading"`
+	SpeedThroughWaterKnots float64 `json:"speedThroughWaterKnots"`
+	SpeedThroughWaterKPH   float64 `json:"speedThroughWaterKph"`
+}
+
+type MWVData struct {
+	WindAngle     float64 `json:"windAngle"`
+	Reference     string  `json:"reference"`
+	WindSpeed     float64 `json:"windSpeed"`
+	WindSpeedUnit string  `json:"windSpeedUnit"`
+	Status        string  `json:"status"`
+}
+
+// IsSupportedNMEASentenceType returns true if sentences of the given type are decoded into NMEARecord data.
+func IsSupportedNMEASentenceType(sentenceType string) bool {
+	switch sentenceType {
+	case nmea.TypeGGA, nmea.TypeGLL, nmea.TypeRMC, nmea.TypeVTG, nmea.TypeZDA, nmea.TypeHDT, nmea.TypeTHS,
+		nmea.TypeDBT, nmea.TypeDPT, nmea.TypeVHW, TypeMWV:
+		return true
+	}
+	return false
+}
+
+func newNMEAData(sentence nmea.Sentence) any {
+	switch s := sentence.(type) {
+	case nmea.GGA:
+		return &GGAData{
+			Time:          formatNMEATime(s.Time),
+			Latitude:      s.Latitude,
+			Longitude:     s.Longitude,
+			FixQuality:    s.FixQuality,
+			NumSatellites: s.NumSatellites,
+			HDOP:          s.HDOP,
+			Altitude:      s.Altitude,
+			Separation:    s.Separation,
+			DGPSAge:       s.DGPSAge,
+			DGPSId:        s.DGPSId,
+		}
+	case nmea.GLL:
+		return &GLLData{
+			Time:      formatNMEATime(s.Time),
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Validity:  s.Validity,
+		}
+	case nmea.RMC:
+		return &RMCData{
+			Date:      formatNMEADate(s.Date),
+			Time:      formatNMEATime(s.Time),
+			Validity:  s.Validity,
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Speed:     s.Speed,
+			Course:    s.Course,
+			Variation: s.Variation,
+		}
+	case nmea.VTG:
+		return &VTGData{
+			TrueTrack:        s.TrueTrack,
+			MagneticTrack:    s.MagneticTrack,
+			GroundSpeedKnots: s.GroundSpeedKnots,
+			GroundSpeedKPH:   s.GroundSpeedKPH,
+		}
+	case nmea.ZDA:
+		return &ZDAData{
+			Date:          fmt.Sprintf("%04d-%02d-%02d", s.Year, s.Month, s.Day),
+			Time:          formatNMEATime(s.Time),
+			OffsetHours:   s.OffsetHours,
+			OffsetMinutes: s.OffsetMinutes,
+		}
+	case nmea.HDT:
+		return &HDTData{
+			Heading: s.Heading,
+			True:    s.True,
+		}
+	case nmea.THS:
+		return &THSData{
+			Heading: s.Heading,
+			Status:  s.Status,
+		}
+	case nmea.DBT:
+		return &DBTData{
+			DepthFeet:    s.DepthFeet,
+			DepthMeters:  s.DepthMeters,
+			DepthFathoms: s.DepthFathoms,
+		}
+	case nmea.DPT:
+		return &DPTData{
+			Depth:      s.Depth,
+			Offset:     s.Offset,
+			RangeScale: s.RangeScale,
+		}
+	case nmea.VHW:
+		return &VHWData{
+			TrueHeading:            s.TrueHeading,
+			MagneticHeading:        s.MagneticHeading,
+			SpeedThroughWaterKnots: s.SpeedThroughWaterKnots,
+			SpeedThroughWaterKPH:   s.SpeedThroughWaterKPH,
+		}
+	case MWV:
+		return &MWVData{
+			WindAngle:     s.WindAngle,
+			Reference:     s.Reference,
+			WindSpeed:     s.WindSpeed,
+			WindSpeedUnit: s.WindSpeedUnit,
+			Status:        s.Status,
+		}
+	}
+	return nil
+}
+
+func formatNMEATime(t nmea.Time) string {
+	if !t.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", t.Hour, t.Minute, t.Second, t.Millisecond)
+}
+
+func formatNMEADate(d nmea.Date) string {
+	if !d.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", NMEAYear(d.YY), d.MM, d.DD)
+}
+
+// NMEAYear expands a two-digit NMEA year, treating 80-99 as 1980-1999.
+func NMEAYear(yy int) int {
+	if yy >= 80 {
+		return 1900 + yy
+	}
+	return 2000 + yy
+}
@@ -0,0 +1,73 @@
+package format
+
+import (
+	nmea "github.com/adrianmo/go-nmea"
+)
+
+// NMEARecordReaderStats counts the sentences seen by a NMEARecordReader.
+type NMEARecordReaderStats struct {
+	Decoded          int            `json:"decoded"`
+	Unsupported      map[string]int `json:"unsupported"`
+	Malformed        int            `json:"malformed"`
+	ChecksumFailures int            `json:"checksumFailures"`
+	ParseErrors      int            `json:"parseErrors"`
+}
+
+type NMEARecordReader struct {
+	loggerRecordReader *LoggerRecordReader
+	stats              NMEARecordReaderStats
+}
+
+func NewNMEARecordReader(loggerRecordReader *LoggerRecordReader) *NMEARecordReader {
+	return &NMEARecordReader{
+		loggerRecordReader: loggerRecordReader,
+		stats: NMEARecordReaderStats{
+			Unsupported: make(map[string]int),
+		},
+	}
+}
+
+func (reader *NMEARecordReader) Stats() NMEARecordReaderStats {
+	return reader.stats
+}
+
+func (reader *NMEARecordReader) ReadNMEARecord() (*NMEARecord, error) {
+	for {
+		loggerRecord, err := reader.loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			return nil, err
+		}
+		if loggerRecord == nil {
+			return nil, nil
+		}
+
+		sentenceInfo, err := ParseSentenceInfo(loggerRecord.NMEA)
+		if err != nil {
+			reader.stats.Malformed++
+			continue
+		}
+		if !sentenceInfo.ChecksumValid {
+			reader.stats.ChecksumFailures++
+			continue
+		}
+		if sentenceInfo.Encapsulated || !IsSupportedNMEASentenceType(sentenceInfo.Type) {
+			reader.stats.Unsupported[sentenceInfo.Prefix()]++
+			continue
+		}
+
+		sentence, err := nmea.Parse(loggerRecord.NMEA)
+		if err != nil {
+			reader.stats.ParseErrors++
+			continue
+		}
+
+		reader.stats.Decoded++
+		return &NMEARecord{
+			Timestamp: loggerRecord.Timestamp,
+			Talker:    sentence.TalkerID(),
+			Type:      sentence.DataType(),
+			Data:      newNMEAData(sentence),
+			Source:    loggerRecord.Source,
+		}, nil
+	}
+}
@@ -0,0 +1,84 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SentenceInfo describes the envelope of a raw NMEA 0183 sentence.
+type SentenceInfo struct {
+	Encapsulated  bool
+	Talker        string
+	Type          string
+	Fields        []string
+	ChecksumValid bool
+}
+
+// Prefix returns the talker and sentence type, e.g. "GPRMC".
+func (info *SentenceInfo) Prefix() string {
+	return info.Talker + info.Type
+}
+
+// ParseSentenceInfo parses the envelope of a raw NMEA 0183 sentence without decoding its fields. An error is returned
+// if the sentence is malformed. A checksum mismatch is not an error, and is reported via ChecksumValid.
+func ParseSentenceInfo(raw string) (*SentenceInfo, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, `\`) {
+		p := strings.Index(raw[1:], `\`)
+		if p < 0 {
+			return nil, fmt.Errorf("unterminated tag block")
+		}
+		raw = raw[p+2:]
+	}
+	if raw == "" {
+		return nil, fmt.Errorf("empty sentence")
+	}
+
+	var encapsulated bool
+	switch raw[0] {
+	case '$':
+	case '!':
+		encapsulated = true
+	default:
+		return nil, fmt.Errorf("sentence does not start with '$' or '!'")
+	}
+
+	p := strings.LastIndex(raw, "*")
+	if p < 0 {
+		return nil, fmt.Errorf("sentence does not contain checksum")
+	}
+	body := raw[1:p]
+	checksum, err := strconv.ParseUint(raw[p+1:], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum")
+	}
+	var computedChecksum uint8
+	for i := 0; i < len(body); i++ {
+		computedChecksum ^= body[i]
+	}
+
+	fields := strings.Split(body, ",")
+	talker, sentenceType := splitSentencePrefix(fields[0])
+	if sentenceType == "" {
+		return nil, fmt.Errorf("invalid sentence prefix")
+	}
+
+	return &SentenceInfo{
+		Encapsulated:  encapsulated,
+		Talker:        talker,
+		Type:          sentenceType,
+		Fields:        fields[1:],
+		ChecksumValid: uint8(checksum) == computedChecksum,
+	}, nil
+}
+
+func splitSentencePrefix(s string) (string, string) {
+	if strings.HasPrefix(s, "P") {
+		return "P", s[1:]
+	}
+	if len(s) < 2 {
+		return s, ""
+	}
+	return s[:2], s[2:]
+}
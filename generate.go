@@ -1,3 +1,4 @@
 package main
 
 //go:generate task generate-ui
+//go:generate sh -c "go run . ais schema > schema/ais-message.schema.json"
@@ -0,0 +1,88 @@
+package geo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Circle is a circular area around a point. Radius is in metres.
+type Circle struct {
+	Name   string
+	Center Point
+	Radius float64
+}
+
+func (circle *Circle) Contains(p Point) bool {
+	return Distance(circle.Center, p) <= circle.Radius
+}
+
+// LoadCircles loads a list of points from a CSV file with name, latitude and longitude columns, and an optional radius
+// column in metres. The first row is a header. Points without a radius get defaultRadius.
+func LoadCircles(path string, defaultRadius float64) ([]*Circle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no header", path)
+	}
+	columnMap := make(map[string]int)
+	for i, name := range records[0] {
+		columnMap[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "latitude", "longitude"} {
+		_, ok := columnMap[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing %s column", path, name)
+		}
+	}
+
+	var circles []*Circle
+	for i, record := range records[1:] {
+		value := func(name string) string {
+			column, ok := columnMap[name]
+			if !ok || (column >= len(record)) {
+				return ""
+			}
+			return strings.TrimSpace(record[column])
+		}
+		latitude, err := strconv.ParseFloat(value("latitude"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid latitude", path, i+2)
+		}
+		longitude, err := strconv.ParseFloat(value("longitude"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid longitude", path, i+2)
+		}
+		radius := defaultRadius
+		if value("radius") != "" {
+			radius, err = strconv.ParseFloat(value("radius"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid radius", path, i+2)
+			}
+		}
+		circles = append(circles, &Circle{
+			Name: value("name"),
+			Center: Point{
+				Longitude: longitude,
+				Latitude:  latitude,
+			},
+			Radius: radius,
+		})
+	}
+	if len(circles) == 0 {
+		return nil, fmt.Errorf("%s: no points found", path)
+	}
+	return circles, nil
+}
@@ -0,0 +1,51 @@
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadius is the mean radius of the Earth, in metres.
+const EarthRadius = 6371008.8
+
+// MetresPerNauticalMile is the length of a nautical mile, in metres.
+const MetresPerNauticalMile = 1852
+
+// Distance returns the great-circle distance between two points, in metres.
+func Distance(a Point, b Point) float64 {
+	lat1 := toRadians(a.Latitude)
+	lat2 := toRadians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := toRadians(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Bearing returns the initial great-circle bearing from a to b, in degrees clockwise from true north (0 to 360).
+func Bearing(a Point, b Point) float64 {
+	lat1 := toRadians(a.Latitude)
+	lat2 := toRadians(b.Latitude)
+	dLon := toRadians(b.Longitude - a.Longitude)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	bearing := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(bearing+360, 360)
+}
+
+// Destination returns the point reached by travelling distance metres from p along the great circle with the given
+// initial bearing, in degrees clockwise from true north.
+func Destination(p Point, bearing float64, distance float64) Point {
+	lat1 := toRadians(p.Latitude)
+	lon1 := toRadians(p.Longitude)
+	theta := toRadians(bearing)
+	delta := distance / EarthRadius
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lon2 := lon1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+	return Point{
+		Longitude: math.Mod(lon2*180/math.Pi+540, 360) - 180,
+		Latitude:  lat2 * 180 / math.Pi,
+	}
+}
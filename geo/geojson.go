@@ -0,0 +1,106 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type geoJsonObject struct {
+	Type        string           `json:"type"`
+	Features    []*geoJsonObject `json:"features"`
+	Geometry    *geoJsonObject   `json:"geometry"`
+	Geometries  []*geoJsonObject `json:"geometries"`
+	Properties  map[string]any   `json:"properties"`
+	Coordinates json.RawMessage  `json:"coordinates"`
+}
+
+// LoadPolygons loads the polygons contained in a GeoJSON file. FeatureCollection, Feature, GeometryCollection, Polygon
+// and MultiPolygon objects are supported; other geometries are ignored. Polygons are named after the "name" property of
+// their feature, if any.
+func LoadPolygons(path string) ([]*Polygon, error) {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var object geoJsonObject
+	err = json.Unmarshal(jsonBytes, &object)
+	if err != nil {
+		return nil, err
+	}
+	var polygons []*Polygon
+	err = collectPolygons(&object, "", &polygons)
+	if err != nil {
+		return nil, err
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("%s: no polygons found", path)
+	}
+	return polygons, nil
+}
+
+func collectPolygons(object *geoJsonObject, name string, polygons *[]*Polygon) error {
+	switch object.Type {
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			err := collectPolygons(feature, "", polygons)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Feature":
+		if object.Geometry == nil {
+			return nil
+		}
+		featureName, _ := object.Properties["name"].(string)
+		return collectPolygons(object.Geometry, featureName, polygons)
+
+	case "GeometryCollection":
+		for _, geometry := range object.Geometries {
+			err := collectPolygons(geometry, name, polygons)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Polygon":
+		var coordinates [][][]float64
+		err := json.Unmarshal(object.Coordinates, &coordinates)
+		if err != nil {
+			return err
+		}
+		*polygons = append(*polygons, newPolygon(name, coordinates))
+
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		err := json.Unmarshal(object.Coordinates, &coordinates)
+		if err != nil {
+			return err
+		}
+		for _, polygonCoordinates := range coordinates {
+			*polygons = append(*polygons, newPolygon(name, polygonCoordinates))
+		}
+	}
+	return nil
+}
+
+func newPolygon(name string, coordinates [][][]float64) *Polygon {
+	polygon := &Polygon{
+		Name: name,
+	}
+	for _, ringCoordinates := range coordinates {
+		var ring []Point
+		for _, position := range ringCoordinates {
+			if len(position) < 2 {
+				continue
+			}
+			ring = append(ring, Point{
+				Longitude: position[0],
+				Latitude:  position[1],
+			})
+		}
+		polygon.Rings = append(polygon.Rings, ring)
+	}
+	return polygon
+}
@@ -0,0 +1,76 @@
+package geo
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// BoundingBox is an axis-aligned box in decimal degrees.
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+func (bbox BoundingBox) Contains(p Point) bool {
+	return (p.Longitude >= bbox.MinLongitude) && (p.Longitude <= bbox.MaxLongitude) &&
+		(p.Latitude >= bbox.MinLatitude) && (p.Latitude <= bbox.MaxLatitude)
+}
+
+// Polygon is a polygon with an outer ring and optional holes. Rings need not be closed.
+type Polygon struct {
+	Name  string
+	Rings [][]Point
+}
+
+func (polygon *Polygon) Contains(p Point) bool {
+	if len(polygon.Rings) == 0 {
+		return false
+	}
+	if !ringContains(polygon.Rings[0], p) {
+		return false
+	}
+	for _, hole := range polygon.Rings[1:] {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// BoundingBox returns the bounding box of the outer ring.
+func (polygon *Polygon) BoundingBox() BoundingBox {
+	var bbox BoundingBox
+	for i, p := range polygon.Rings[0] {
+		if (i == 0) || (p.Longitude < bbox.MinLongitude) {
+			bbox.MinLongitude = p.Longitude
+		}
+		if (i == 0) || (p.Longitude > bbox.MaxLongitude) {
+			bbox.MaxLongitude = p.Longitude
+		}
+		if (i == 0) || (p.Latitude < bbox.MinLatitude) {
+			bbox.MinLatitude = p.Latitude
+		}
+		if (i == 0) || (p.Latitude > bbox.MaxLatitude) {
+			bbox.MaxLatitude = p.Latitude
+		}
+	}
+	return bbox
+}
+
+func ringContains(ring []Point, p Point) bool {
+	contains := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a := ring[i]
+		b := ring[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) {
+			x := (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if p.Longitude < x {
+				contains = !contains
+			}
+		}
+	}
+	return contains
+}
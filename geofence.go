@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/BertoldVdb/go-ais/aisnmea"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	GeofenceEnter = "enter"
+	GeofenceExit  = "exit"
+	GeofenceDwell = "dwell"
+)
+
+// GeofenceEvent is a vessel entering, leaving or dwelling in a zone. Dwell is the time, in seconds, since the vessel
+// entered the zone (exit and dwell events only).
+type GeofenceEvent struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	MMSI      uint32    `json:"mmsi"`
+	Zone      string    `json:"zone"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Dwell     *float64  `json:"dwell,omitempty"`
+}
+
+// geofenceZone is a named area, made up of one or more polygons.
+type geofenceZone struct {
+	name     string
+	polygons []*geo.Polygon
+	bboxes   []geo.BoundingBox
+}
+
+func (zone *geofenceZone) contains(p geo.Point) bool {
+	for i, polygon := range zone.polygons {
+		if zone.bboxes[i].Contains(p) && polygon.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// geofenceVisit is a vessel's stay in a zone.
+type geofenceVisit struct {
+	entered    int64
+	dwellAlert bool
+}
+
+type geofenceVessel struct {
+	timestamp int64
+	visits    map[string]*geofenceVisit
+}
+
+// geofenceEngine tracks which zones each vessel is in, and turns position reports into geofence events.
+type geofenceEngine struct {
+	zones     []*geofenceZone
+	dwell     time.Duration
+	vesselMap map[uint32]*geofenceVessel
+}
+
+// newGeofenceEngine returns an engine for the given polygons. Polygons with the same name form a single zone; unnamed
+// polygons are named after their position in the file. A dwell event is emitted once a vessel has been in a zone for
+// the dwell duration; a dwell duration of 0 disables dwell events.
+func newGeofenceEngine(polygons []*geo.Polygon, dwell time.Duration) *geofenceEngine {
+	engine := &geofenceEngine{
+		dwell:     dwell,
+		vesselMap: make(map[uint32]*geofenceVessel),
+	}
+	zoneMap := make(map[string]*geofenceZone)
+	for i, polygon := range polygons {
+		if len(polygon.Rings) == 0 {
+			continue
+		}
+		name := polygon.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		zone, ok := zoneMap[name]
+		if !ok {
+			zone = &geofenceZone{
+				name: name,
+			}
+			zoneMap[name] = zone
+			engine.zones = append(engine.zones, zone)
+		}
+		zone.polygons = append(zone.polygons, polygon)
+		zone.bboxes = append(zone.bboxes, polygon.BoundingBox())
+	}
+	return engine
+}
+
+// evaluate evaluates a decoded AIS record, and returns the resulting events. Records without a position, and positions
+// older than the last one evaluated for the same vessel, are ignored.
+func (engine *geofenceEngine) evaluate(record *format.AISRecord) []*GeofenceEvent {
+	latitude, longitude, ok := format.AISPosition(record.AIS.Packet)
+	if !ok {
+		return nil
+	}
+	mmsi := record.AIS.Packet.GetHeader().UserID
+	vessel, ok := engine.vesselMap[mmsi]
+	if !ok {
+		vessel = &geofenceVessel{
+			visits: make(map[string]*geofenceVisit),
+		}
+		engine.vesselMap[mmsi] = vessel
+	} else if record.Timestamp < vessel.timestamp {
+		return nil
+	}
+	vessel.timestamp = record.Timestamp
+
+	p := geo.Point{
+		Longitude: longitude,
+		Latitude:  latitude,
+	}
+	newEvent := func(event string, zone *geofenceZone, visit *geofenceVisit) *GeofenceEvent {
+		geofenceEvent := &GeofenceEvent{
+			Event:     event,
+			Time:      time.UnixMilli(record.Timestamp).UTC(),
+			MMSI:      mmsi,
+			Zone:      zone.name,
+			Latitude:  latitude,
+			Longitude: longitude,
+		}
+		if event != GeofenceEnter {
+			dwell := time.Duration(record.Timestamp-visit.entered) * time.Millisecond
+			seconds := dwell.Seconds()
+			geofenceEvent.Dwell = &seconds
+		}
+		return geofenceEvent
+	}
+
+	var events []*GeofenceEvent
+	for _, zone := range engine.zones {
+		visit, inside := vessel.visits[zone.name]
+		switch {
+		case zone.contains(p) && !inside:
+			visit = &geofenceVisit{
+				entered: record.Timestamp,
+			}
+			vessel.visits[zone.name] = visit
+			events = append(events, newEvent(GeofenceEnter, zone, visit))
+
+		case zone.contains(p):
+			if (engine.dwell > 0) && !visit.dwellAlert &&
+				(record.Timestamp-visit.entered >= engine.dwell.Milliseconds()) {
+				visit.dwellAlert = true
+				events = append(events, newEvent(GeofenceDwell, zone, visit))
+			}
+
+		case inside:
+			delete(vessel.visits, zone.name)
+			events = append(events, newEvent(GeofenceExit, zone, visit))
+		}
+	}
+	return events
+}
+
+func doAisGeofence(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+
+	zonesFile := cmd.String(zonesFlag.Name)
+	if zonesFile == "" {
+		return fmt.Errorf("--%s is required", zonesFlag.Name)
+	}
+	polygons, err := geo.LoadPolygons(zonesFile)
+	if err != nil {
+		return err
+	}
+	engine := newGeofenceEngine(polygons, cmd.Duration(dwellFlag.Name))
+
+	writer := format.NewJsonlWriter(os.Stdout)
+	return readAISRecords(inputFiles, aisRecordOptions{MMSIs: mmsis, DedupeWindow: dedupeWindow}, func(aisRecord *format.AISRecord) error {
+		for _, event := range engine.evaluate(aisRecord) {
+			err := writer.WriteRecord(event)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// geofenceLogger evaluates the sentences received by the logger, and appends the resulting events to a JSONL file.
+type geofenceLogger struct {
+	engine  *geofenceEngine
+	decoder *aisnmea.NMEACodec
+	file    *os.File
+	writer  *format.JsonlWriter
+}
+
+func newGeofenceLogger(engine *geofenceEngine, path string) (*geofenceLogger, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &geofenceLogger{
+		engine:  engine,
+		decoder: format.NewAISDecoder(),
+		file:    file,
+		writer:  format.NewJsonlWriter(file),
+	}, nil
+}
+
+func (geofenceLogger *geofenceLogger) Close() error {
+	return geofenceLogger.file.Close()
+}
+
+// add decodes a logged sentence, and writes the events resulting from it. Sentences that cannot be decoded are
+// ignored.
+func (geofenceLogger *geofenceLogger) add(record *format.LoggerRecord) error {
+	decoded, err := geofenceLogger.decoder.ParseSentence(record.NMEA)
+	if (err != nil) || (decoded == nil) || (decoded.Packet == nil) {
+		return nil
+	}
+	aisRecord := &format.AISRecord{
+		Timestamp: record.Timestamp,
+		AIS:       decoded,
+	}
+	for _, event := range geofenceLogger.engine.evaluate(aisRecord) {
+		log.Debug("geofence event",
+			slog.String("event", event.Event),
+			slog.Any("mmsi", event.MMSI),
+			slog.String("zone", event.Zone),
+		)
+		err = geofenceLogger.writer.WriteRecord(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	github.com/BertoldVdb/go-ais v0.4.0
+	github.com/adrianmo/go-nmea v1.3.0
 	github.com/arthurkiller/rollingwriter v1.1.3
 	github.com/coder/websocket v1.8.14
 	github.com/dsnet/compress v0.0.1
@@ -14,7 +15,6 @@ require (
 )
 
 require (
-	github.com/adrianmo/go-nmea v1.3.0 // indirect
 	github.com/creack/goselect v0.1.2 // indirect
 	github.com/robfig/cron v1.1.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
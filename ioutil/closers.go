@@ -4,11 +4,14 @@ import "io"
 
 type Closers []io.Closer
 
+// Close closes the closers in reverse order, and returns the first error.
 func (closers Closers) Close() error {
-	if closers != nil {
-		for i := len(closers) - 1; i >= 0; i-- {
-			_ = closers[i].Close()
+	var firstErr error
+	for i := len(closers) - 1; i >= 0; i-- {
+		err := closers[i].Close()
+		if (err != nil) && (firstErr == nil) {
+			firstErr = err
 		}
 	}
-	return nil
+	return firstErr
 }
@@ -0,0 +1,50 @@
+package ioutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DirWriter creates files in a directory one at a time, mirroring the API of zip.Writer. Creating a file closes the
+// previous one.
+type DirWriter struct {
+	dir     string
+	current *os.File
+}
+
+func NewDirWriter(dir string) (*DirWriter, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &DirWriter{
+		dir: dir,
+	}, nil
+}
+
+func (writer *DirWriter) Create(name string) (io.Writer, error) {
+	err := writer.closeCurrent()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(writer.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	writer.current = f
+	return f, nil
+}
+
+func (writer *DirWriter) Close() error {
+	return writer.closeCurrent()
+}
+
+func (writer *DirWriter) closeCurrent() error {
+	if writer.current == nil {
+		return nil
+	}
+	err := writer.current.Close()
+	writer.current = nil
+	return err
+}
@@ -0,0 +1,66 @@
+package ioutil
+
+import (
+	"io"
+)
+
+// MultiFileReader reads a sequence of (possibly compressed) files one after another, opening each file only when it is
+// reached. A line terminator is inserted between files if a file does not end with one.
+type MultiFileReader struct {
+	paths    []string
+	current  io.ReadCloser
+	lastByte byte
+}
+
+func OpenFilesForReading(paths []string) io.ReadCloser {
+	return &MultiFileReader{
+		paths:    paths,
+		lastByte: '\n',
+	}
+}
+
+func (reader *MultiFileReader) Read(buf []byte) (n int, err error) {
+	for {
+		if reader.current == nil {
+			if len(reader.paths) == 0 {
+				return 0, io.EOF
+			}
+			reader.current, err = OpenFileForReading(reader.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			reader.paths = reader.paths[1:]
+		}
+
+		n, err = reader.current.Read(buf)
+		if n > 0 {
+			reader.lastByte = buf[n-1]
+		}
+		if err == io.EOF {
+			_ = reader.current.Close()
+			reader.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			if reader.lastByte != '\n' {
+				if len(buf) == 0 {
+					return 0, nil
+				}
+				buf[0] = '\n'
+				reader.lastByte = '\n'
+				return 1, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (reader *MultiFileReader) Close() error {
+	if reader.current != nil {
+		_ = reader.current.Close()
+		reader.current = nil
+	}
+	reader.paths = nil
+	return nil
+}
@@ -36,6 +36,10 @@ func (wrapper *ReadCloserWrapper) Close() error {
 }
 
 func OpenFileForReading(path string) (io.ReadCloser, error) {
+	if IsStdioPath(path) {
+		return io.NopCloser(os.Stdin), nil
+	}
+
 	var closers Closers
 
 	f, err := os.Open(path)
@@ -0,0 +1,15 @@
+package ioutil
+
+const (
+	// StdioPath is the path that stands for standard input or output.
+	StdioPath = "-"
+
+	// EscapedStdioPath stands for StdioPath on the command line. The command line parser stops at a "-" argument and
+	// drops the arguments after it, so "-" arguments are escaped before parsing.
+	EscapedStdioPath = "<stdio>"
+)
+
+// IsStdioPath reports whether path stands for standard input or output.
+func IsStdioPath(path string) bool {
+	return (path == StdioPath) || (path == EscapedStdioPath)
+}
@@ -26,13 +26,18 @@ func (wrapper *WriteCloserWrapper) Write(buf []byte) (n int, err error) {
 	return wrapper.w.Write(buf)
 }
 
+// Close closes the writer, e.g. to flush a compressor, and then the underlying closers. It returns the first error.
 func (wrapper *WriteCloserWrapper) Close() error {
+	var err error
 	closer, ok := wrapper.w.(io.Closer)
 	if ok && (closer != nil) {
-		_ = closer.Close()
+		err = closer.Close()
 	}
-	_ = wrapper.closers.Close()
-	return nil
+	closersErr := wrapper.closers.Close()
+	if err != nil {
+		return err
+	}
+	return closersErr
 }
 
 func OpenFileForWriting(path string) (io.WriteCloser, string, error) {
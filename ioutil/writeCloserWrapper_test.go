@@ -0,0 +1,54 @@
+package ioutil
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+type testCloser struct {
+	err    error
+	closed *[]string
+	name   string
+}
+
+func (closer testCloser) Write(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func (closer testCloser) Close() error {
+	*closer.closed = append(*closer.closed, closer.name)
+	return closer.err
+}
+
+func TestWriteCloserWrapperClose(t *testing.T) {
+	errWriter := errors.New("writer")
+	errFile := errors.New("file")
+	tests := []struct {
+		name      string
+		writerErr error
+		fileErr   error
+		want      error
+	}{
+		{"no error", nil, nil, nil},
+		{"writer error", errWriter, nil, errWriter},
+		{"file error", nil, errFile, errFile},
+		{"both errors", errWriter, errFile, errWriter},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var closed []string
+			wrapper := NewWriteCloserWrapper(
+				testCloser{err: test.writerErr, closed: &closed, name: "writer"},
+				[]io.Closer{testCloser{err: test.fileErr, closed: &closed, name: "file"}},
+			)
+			err := wrapper.Close()
+			if !errors.Is(err, test.want) || ((err == nil) != (test.want == nil)) {
+				t.Errorf("Close() error = %v, want %v", err, test.want)
+			}
+			if (len(closed) != 2) || (closed[0] != "writer") || (closed[1] != "file") {
+				t.Errorf("closed = %v, want [writer file]", closed)
+			}
+		})
+	}
+}
@@ -5,16 +5,27 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/arthurkiller/rollingwriter"
 	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/ngyewch/nmea-logger/systemd"
 	"github.com/urfave/cli/v3"
 	"go.bug.st/serial"
 )
 
+const (
+	// reopenMinDelay is the delay before retrying to reopen the serial port after a failed attempt or a failed read.
+	// The delay doubles after each failure, up to reopenMaxDelay, until a line is received again.
+	reopenMinDelay = 1 * time.Second
+	reopenMaxDelay = 1 * time.Minute
+)
+
 func doLog(ctx context.Context, cmd *cli.Command) error {
 	outputDir := cmd.String(outputDirFlag.Name)
 	serialPort := cmd.String(serialPortFlag.Name)
@@ -22,6 +33,9 @@ func doLog(ctx context.Context, cmd *cli.Command) error {
 	dataBits := cmd.Int(dataBitsFlag.Name)
 	parity0 := cmd.String(parityFlag.Name)
 	stopBits0 := cmd.String(stopBitsFlag.Name)
+	silenceThreshold := cmd.Duration(silenceThresholdFlag.Name)
+	exitOnSilence := cmd.Bool(exitOnSilenceFlag.Name)
+	zonesFile := cmd.String(zonesFlag.Name)
 
 	parity := serial.NoParity
 	switch parity0 {
@@ -56,9 +70,11 @@ func doLog(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return err
 	}
-	defer func(port serial.Port) {
-		_ = port.Close()
-	}(port)
+	defer func() {
+		if port != nil {
+			_ = port.Close()
+		}
+	}()
 
 	rollingWriterConfig := rollingwriter.NewDefaultConfig()
 	rollingWriterConfig.LogPath = outputDir
@@ -73,28 +89,175 @@ func doLog(ctx context.Context, cmd *cli.Command) error {
 	}(rollingWriter)
 	w := io.MultiWriter(os.Stdout, rollingWriter)
 
-	scanner := bufio.NewScanner(port)
-	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) == "" {
-			continue
-		}
-		record := &format.LoggerRecord{
-			Timestamp: time.Now().UnixMilli(),
-			NMEA:      scanner.Text(),
-		}
-		jsonBytes, err := json.Marshal(record)
+	var geofence *geofenceLogger
+	if zonesFile != "" {
+		polygons, err := geo.LoadPolygons(zonesFile)
 		if err != nil {
 			return err
 		}
-		_, err = w.Write(jsonBytes)
+		geofenceOutput := cmd.String(geofenceOutputFlag.Name)
+		if geofenceOutput == "" {
+			geofenceOutput = filepath.Join(outputDir, "geofence.jsonl")
+		}
+		geofence, err = newGeofenceLogger(newGeofenceEngine(polygons, cmd.Duration(dwellFlag.Name)), geofenceOutput)
 		if err != nil {
 			return err
 		}
-		_, err = w.Write([]byte{'\n'})
+		defer func(geofence *geofenceLogger) {
+			_ = geofence.Close()
+		}(geofence)
+	}
+
+	watchdogInterval, err := systemd.WatchdogInterval()
+	if err != nil {
+		return err
+	}
+	var watchdogC <-chan time.Time
+	if watchdogInterval > 0 {
+		watchdogTicker := time.NewTicker(watchdogInterval / 2)
+		defer watchdogTicker.Stop()
+		watchdogC = watchdogTicker.C
+	}
+	var silenceC <-chan time.Time
+	if silenceThreshold > 0 {
+		silenceTicker := time.NewTicker(min(silenceThreshold/4, 1*time.Second))
+		defer silenceTicker.Stop()
+		silenceC = silenceTicker.C
+	}
+
+	_, err = systemd.Notify("READY=1")
+	if err != nil {
+		log.Warn("error notifying service manager",
+			slog.Any("err", err),
+		)
+	}
+
+	lines, scanErrors := scanLines(port)
+	lastReceived := time.Now()
+	var reopenC <-chan time.Time
+	reopenDelay := reopenMinDelay
+	retryReopen := func() {
+		reopenC = time.After(reopenDelay)
+		reopenDelay = min(reopenDelay*2, reopenMaxDelay)
+	}
+	reopen := func() {
+		port, err = serial.Open(serialPort, mode)
 		if err != nil {
-			return err
+			port = nil
+			log.Warn("error reopening serial port",
+				slog.String("serialPort", serialPort),
+				slog.Duration("retryIn", reopenDelay),
+				slog.Any("err", err),
+			)
+			retryReopen()
+			return
+		}
+		reopenC = nil
+		lines, scanErrors = scanLines(port)
+		lastReceived = time.Now()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case line, ok := <-lines:
+			if !ok {
+				// The port was closed or disconnected; reopen it after a delay
+				err = <-scanErrors
+				if err == nil {
+					err = io.EOF
+				}
+				log.Warn("error reading serial port",
+					slog.String("serialPort", serialPort),
+					slog.Duration("retryIn", reopenDelay),
+					slog.Any("err", err),
+				)
+				_ = port.Close()
+				port = nil
+				lines = nil
+				retryReopen()
+				continue
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			lastReceived = time.Now()
+			reopenDelay = reopenMinDelay
+			record := &format.LoggerRecord{
+				Timestamp: lastReceived.UnixMilli(),
+				NMEA:      line,
+			}
+			jsonBytes, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(jsonBytes)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte{'\n'})
+			if err != nil {
+				return err
+			}
+			if geofence != nil {
+				// Geofencing must not stop the logging of sentences
+				err = geofence.add(record)
+				if err != nil {
+					log.Warn("error writing geofence events",
+						slog.Any("err", err),
+					)
+				}
+			}
+
+		case <-watchdogC:
+			if (port != nil) && (silenceThreshold > 0) && (time.Since(lastReceived) >= silenceThreshold) {
+				continue
+			}
+			_, err = systemd.Notify("WATCHDOG=1")
+			if err != nil {
+				log.Warn("error notifying service manager",
+					slog.Any("err", err),
+				)
+			}
+
+		case <-silenceC:
+			silence := time.Since(lastReceived)
+			if (port == nil) || (silence < silenceThreshold) {
+				continue
+			}
+			log.Error("no data received from serial port",
+				slog.String("serialPort", serialPort),
+				slog.Duration("silence", silence),
+			)
+			if exitOnSilence {
+				return cli.Exit("no data received from serial port", 1)
+			}
+
+			_ = port.Close()
+			for range lines {
+			}
+			lines = nil
+			reopen()
+
+		case <-reopenC:
+			reopen()
 		}
 	}
+}
 
-	return nil
+// scanLines reads lines from r in the background. The lines channel is closed when reading stops, after which the
+// scan error (if any) is available on the errors channel.
+func scanLines(r io.Reader) (<-chan string, <-chan error) {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		errs <- scanner.Err()
+	}()
+	return lines, errs
 }
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	slogUtils "github.com/ngyewch/go-clibase/slog-utils"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
 	"github.com/urfave/cli/v3"
 )
 
@@ -35,6 +39,12 @@ var (
 		Sources: cli.EnvVars("OUTPUT_DIR"),
 	}
 
+	splitOutputDirFlag = &cli.StringFlag{
+		Name:     "output-dir",
+		Usage:    "output directory",
+		Required: true,
+	}
+
 	serialPortFlag = &cli.StringFlag{
 		Name:     "serial-port",
 		Usage:    "serial port",
@@ -87,6 +97,19 @@ var (
 		},
 	}
 
+	silenceThresholdFlag = &cli.DurationFlag{
+		Name:     "silence-threshold",
+		Usage:    "reopen serial port if no data is received within this duration (0 to disable)",
+		Category: "Watchdog",
+		Sources:  cli.EnvVars("SILENCE_THRESHOLD"),
+	}
+	exitOnSilenceFlag = &cli.BoolFlag{
+		Name:     "exit-on-silence",
+		Usage:    "exit with a non-zero status instead of reopening serial port",
+		Category: "Watchdog",
+		Sources:  cli.EnvVars("EXIT_ON_SILENCE"),
+	}
+
 	listenAddrFlag = &cli.StringFlag{
 		Name:    "listen-addr",
 		Usage:   "listen address",
@@ -106,6 +129,443 @@ var (
 		Sources: cli.EnvVars("PLAYBACK_UPDATE_PERIOD"),
 	}
 
+	outputFlag = &cli.StringFlag{
+		Name:     "output",
+		Aliases:  []string{"o"},
+		Usage:    "output file",
+		Required: true,
+	}
+	maxGapFlag = &cli.DurationFlag{
+		Name:  "max-gap",
+		Usage: "start a new track segment if consecutive fixes are further apart than this (0 to disable)",
+		Value: 1 * time.Minute,
+	}
+	trackNameFlag = &cli.StringFlag{
+		Name:  "track-name",
+		Usage: "track name (defaults to output file name)",
+	}
+
+	gapThresholdFlag = &cli.DurationFlag{
+		Name:  "gap-threshold",
+		Usage: "report gaps between consecutive records longer than this (0 to disable)",
+		Value: 1 * time.Minute,
+	}
+	outputFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "output format (text, json)",
+		Value: "text",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			switch s {
+			case "text", "json":
+			default:
+				return fmt.Errorf("invalid format")
+			}
+			return nil
+		},
+	}
+
+	fromFlag = &cli.TimestampFlag{
+		Name:     "from",
+		Usage:    "include records at or after this time (RFC 3339)",
+		Category: "Filter",
+		Config: cli.TimestampConfig{
+			Timezone: time.UTC,
+			Layouts:  []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"},
+		},
+	}
+	toFlag = &cli.TimestampFlag{
+		Name:     "to",
+		Usage:    "include records before this time (RFC 3339)",
+		Category: "Filter",
+		Config: cli.TimestampConfig{
+			Timezone: time.UTC,
+			Layouts:  []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"},
+		},
+	}
+	sentenceFlag = &cli.StringSliceFlag{
+		Name:     "sentence",
+		Usage:    "include sentence types matching pattern (e.g. VDM, RMC, G??)",
+		Category: "Filter",
+	}
+	talkerFlag = &cli.StringSliceFlag{
+		Name:     "talker",
+		Usage:    "include talkers matching pattern (e.g. AI, G?)",
+		Category: "Filter",
+	}
+	aisTypeFlag = &cli.IntSliceFlag{
+		Name:     "ais-type",
+		Usage:    "include AIS message types",
+		Category: "Filter",
+	}
+	mmsiFlag = &cli.Uint32SliceFlag{
+		Name:     "mmsi",
+		Usage:    "include AIS messages from MMSIs",
+		Category: "Filter",
+	}
+	bboxFlag = &cli.Float64SliceFlag{
+		Name:     "bbox",
+		Usage:    "include AIS positions within bounding box (min-lon,min-lat,max-lon,max-lat)",
+		Category: "Filter",
+	}
+	polygonFlag = &cli.StringFlag{
+		Name:     "polygon",
+		Usage:    "include AIS positions within the polygons of a GeoJSON file",
+		Category: "Filter",
+	}
+
+	dropInvalidFlag = &cli.BoolFlag{
+		Name:  "drop-invalid",
+		Usage: "drop malformed records, and sentences that cannot be parsed or fail the checksum",
+	}
+
+	tagSourceFlag = &cli.BoolFlag{
+		Name:  "tag-source",
+		Usage: "tag each record with the name of its input file",
+	}
+
+	intervalFlag = &cli.DurationFlag{
+		Name:  "interval",
+		Usage: "split into time windows of this length, aligned to UTC (e.g. 1h, 24h)",
+		Validator: func(interval time.Duration) error {
+			if interval < time.Millisecond {
+				return fmt.Errorf("interval must be at least 1ms")
+			}
+			return nil
+		},
+	}
+	maxSizeFlag = &cli.StringFlag{
+		Name:  "max-size",
+		Usage: "start a new chunk once this uncompressed size is reached (e.g. 500M, 2G)",
+	}
+	compressionFlag = &cli.StringFlag{
+		Name:  "compression",
+		Usage: "output compression (gz, bz2, xz, none)",
+		Value: "gz",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			switch s {
+			case "gz", "bz2", "xz", "none":
+			default:
+				return fmt.Errorf("invalid compression")
+			}
+			return nil
+		},
+	}
+	prefixFlag = &cli.StringFlag{
+		Name:  "prefix",
+		Usage: "output file name prefix",
+		Value: "nmea",
+	}
+
+	windowFlag = &cli.DurationFlag{
+		Name:  "window",
+		Usage: "time window over which the clock offset is estimated",
+		Value: 10 * time.Minute,
+		Validator: func(window time.Duration) error {
+			if window < time.Millisecond {
+				return fmt.Errorf("window must be at least 1ms")
+			}
+			return nil
+		},
+	}
+	timeReferenceFlag = &cli.StringSliceFlag{
+		Name:  "reference",
+		Usage: "time references to use (gnss: RMC/ZDA sentences, ais: base station reports)",
+		Value: []string{"gnss", "ais"},
+		Action: func(ctx context.Context, cmd *cli.Command, references []string) error {
+			for _, reference := range references {
+				switch reference {
+				case "gnss", "ais":
+				default:
+					return fmt.Errorf("invalid reference")
+				}
+			}
+			return nil
+		},
+	}
+
+	dedupeWindowFlag = &cli.DurationFlag{
+		Name:  "dedupe-window",
+		Usage: "drop AIS messages with a payload identical to one received within this window (0 to disable)",
+	}
+
+	csvColumnsFlag = &cli.StringSliceFlag{
+		Name:     "csv-columns",
+		Usage:    "CSV position columns, in order (" + strings.Join(format.CsvColumnNames, ", ") + ")",
+		Category: "CSV",
+		Action: func(ctx context.Context, cmd *cli.Command, columns []string) error {
+			return format.ValidateCsvColumns(columns)
+		},
+	}
+	timezoneFlag = &cli.StringFlag{
+		Name:     "timezone",
+		Usage:    "time zone of CSV date times (e.g. UTC, Local, Asia/Singapore)",
+		Category: "CSV",
+		Value:    "UTC",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			_, err := time.LoadLocation(s)
+			return err
+		},
+	}
+	timeLayoutFlag = &cli.StringFlag{
+		Name:     "time-layout",
+		Usage:    "Go time layout of CSV date times",
+		Category: "CSV",
+		Value:    format.DefaultCsvOptions().DateTimeLayout,
+	}
+	etaLayoutFlag = &cli.StringFlag{
+		Name:     "eta-layout",
+		Usage:    "Go time layout of the CSV ETA column",
+		Category: "CSV",
+		Value:    format.DefaultCsvOptions().EtaLayout,
+	}
+	precisionFlag = &cli.IntFlag{
+		Name:     "precision",
+		Usage:    "decimal places of CSV latitudes and longitudes (-1 for full precision)",
+		Category: "CSV",
+		Value:    format.DefaultCsvOptions().CoordinatePrecision,
+	}
+	staticLookaheadFlag = &cli.DurationFlag{
+		Name:     "static-lookahead",
+		Usage:    "hold back CSV rows for up to this long while waiting for the vessel's static data (0 to disable)",
+		Category: "CSV",
+		Value:    format.DefaultCsvOptions().StaticDataLookahead,
+	}
+	delimiterFlag = &cli.StringFlag{
+		Name:     "delimiter",
+		Usage:    "CSV field delimiter (a single character, or tab)",
+		Category: "CSV",
+		Value:    ",",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			_, err := format.ParseCsvDelimiter(s)
+			return err
+		},
+	}
+
+	enrichFlag = &cli.BoolFlag{
+		Name:  "enrich",
+		Usage: "add the flag state, station class, and ship type and navigational status descriptions to JSONL and CSV output",
+	}
+
+	rawFlag = &cli.BoolFlag{
+		Name:  "raw",
+		Usage: "keep AIS \"not available\" values (e.g. latitude 91, heading 511) instead of writing them as nulls or empty cells",
+	}
+
+	convertFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "format of standard output (jsonl, csv, zip, geojson, kml)",
+		Value: "jsonl",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			if !slices.Contains(convertFormats, s) {
+				return fmt.Errorf("invalid format")
+			}
+			return nil
+		},
+	}
+
+	jsonCsvFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "output format (json, csv)",
+		Value: "json",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			switch s {
+			case "json", "csv":
+			default:
+				return fmt.Errorf("invalid format")
+			}
+			return nil
+		},
+	}
+	historyFlag = &cli.BoolFlag{
+		Name:  "history",
+		Usage: "with --format csv, write the static data change history instead of the registry",
+	}
+
+	portsFlag = &cli.StringFlag{
+		Name:  "ports",
+		Usage: "ports, as polygons in a GeoJSON file, or as points in a CSV file with name, latitude, longitude and optional radius columns",
+	}
+	portRadiusFlag = &cli.Float64Flag{
+		Name:  "port-radius",
+		Usage: "radius, in metres, of ports given as points",
+		Value: 1000,
+	}
+	stationarySpeedFlag = &cli.Float64Flag{
+		Name:  "stationary-speed",
+		Usage: "speed over ground, in knots, below which a vessel is moored or anchored",
+		Value: 0.5,
+	}
+	minDwellFlag = &cli.DurationFlag{
+		Name:  "min-dwell",
+		Usage: "minimum duration of a moored or anchored phase; shorter stops are part of the voyage",
+		Value: 30 * time.Minute,
+	}
+	phasesFlag = &cli.BoolFlag{
+		Name:  "phases",
+		Usage: "with --format csv, write the moored, anchored and underway phases instead of the voyages",
+	}
+
+	cpaFlag = &cli.Float64Flag{
+		Name:  "cpa",
+		Usage: "report encounters whose closest point of approach is within this distance, in nautical miles",
+		Value: 0.5,
+	}
+	tcpaFlag = &cli.DurationFlag{
+		Name:  "tcpa",
+		Usage: "report encounters whose closest point of approach is within this time",
+		Value: 12 * time.Minute,
+	}
+	stepFlag = &cli.DurationFlag{
+		Name:  "step",
+		Usage: "interval at which vessel tracks are interpolated and compared",
+		Value: 30 * time.Second,
+	}
+	interpolationGapFlag = &cli.DurationFlag{
+		Name:  "max-gap",
+		Usage: "do not interpolate between positions further apart than this",
+		Value: 3 * time.Minute,
+	}
+
+	zonesFlag = &cli.StringFlag{
+		Name:     "zones",
+		Usage:    "geofence zones, as polygons in a GeoJSON file",
+		Category: "Geofence",
+		Sources:  cli.EnvVars("GEOFENCE_ZONES"),
+	}
+	dwellFlag = &cli.DurationFlag{
+		Name:     "dwell",
+		Usage:    "emit a dwell event once a vessel has been in a zone for this long (0 to disable)",
+		Category: "Geofence",
+		Value:    15 * time.Minute,
+		Sources:  cli.EnvVars("GEOFENCE_DWELL"),
+	}
+	geofenceOutputFlag = &cli.StringFlag{
+		Name:     "geofence-output",
+		Usage:    "file to which geofence events are appended (default: geofence.jsonl in the output directory)",
+		Category: "Geofence",
+		Sources:  cli.EnvVars("GEOFENCE_OUTPUT"),
+	}
+
+	maxSpeedFlag = &cli.Float64Flag{
+		Name:  "max-speed",
+		Usage: "report positions implying a speed above this, in knots",
+		Value: 50,
+	}
+	maxStalenessFlag = &cli.DurationFlag{
+		Name:  "max-staleness",
+		Usage: "report messages whose UTC second differs from the receive time by more than this",
+		Value: 10 * time.Second,
+	}
+	duplicateWindowFlag = &cli.DurationFlag{
+		Name:  "duplicate-window",
+		Usage: "report an MMSI as duplicated if it reports again from an earlier position within this time",
+		Value: 10 * time.Minute,
+	}
+
+	receiverFlag = &cli.StringSliceFlag{
+		Name:  "receiver",
+		Usage: "receiver position ([source=]latitude,longitude); without a source, applies to all sources (default: GNSS fixes in the log)",
+	}
+	sectorsFlag = &cli.IntFlag{
+		Name:  "sectors",
+		Usage: "number of bearing sectors",
+		Value: 36,
+	}
+	percentilesFlag = &cli.Float64SliceFlag{
+		Name:  "percentile",
+		Usage: "range percentiles to compute for each sector",
+		Value: []float64{50, 90, 95},
+	}
+	bandWidthFlag = &cli.Float64Flag{
+		Name:  "band-width",
+		Usage: "width of the distance bands, in nautical miles",
+		Value: 5,
+	}
+	coverageIntervalFlag = &cli.DurationFlag{
+		Name:  "interval",
+		Usage: "length of the time windows over which message types are counted",
+		Value: time.Hour,
+	}
+	coverageFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "output format (geojson, csv)",
+		Value: "geojson",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			switch s {
+			case "geojson", "csv":
+			default:
+				return fmt.Errorf("invalid format")
+			}
+			return nil
+		},
+	}
+	coverageTableFlag = &cli.StringFlag{
+		Name:  "table",
+		Usage: "with --format csv, the table to write (sectors, bands, types)",
+		Value: "sectors",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			switch s {
+			case "sectors", "bands", "types":
+			default:
+				return fmt.Errorf("invalid table")
+			}
+			return nil
+		},
+	}
+
+	scenarioFlag = &cli.StringFlag{
+		Name:  "scenario",
+		Usage: "JSON file describing the vessels to simulate",
+	}
+	randomVesselsFlag = &cli.IntFlag{
+		Name:  "vessels",
+		Usage: "number of vessels roaming randomly within --area",
+	}
+	areaFlag = &cli.Float64SliceFlag{
+		Name:  "area",
+		Usage: "area of the randomly roaming vessels (min-lon,min-lat,max-lon,max-lat)",
+	}
+	durationFlag = &cli.DurationFlag{
+		Name:  "duration",
+		Usage: "simulated time (0 to run until interrupted when streaming)",
+		Value: time.Hour,
+	}
+	startFlag = &cli.TimestampFlag{
+		Name:  "start",
+		Usage: "start time of the simulation when writing JSONL (RFC 3339, default: now)",
+		Config: cli.TimestampConfig{
+			Timezone: time.UTC,
+			Layouts:  []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"},
+		},
+	}
+	seedFlag = &cli.Int64Flag{
+		Name:  "seed",
+		Usage: "random seed, for reproducible simulations (0 for a random seed)",
+	}
+	tcpFlag = &cli.StringFlag{
+		Name:  "tcp",
+		Usage: "stream the sentences in real time to the clients connecting to this address",
+	}
+	udpFlag = &cli.StringFlag{
+		Name:  "udp",
+		Usage: "stream the sentences in real time as datagrams to this address",
+	}
+
+	failOnFlag = &cli.StringFlag{
+		Name:  "fail-on",
+		Usage: "exit with a non-zero status if an issue of at least this severity is found (warning, error, none)",
+		Value: "error",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			_, err := parseSeverity(s)
+			return err
+		},
+	}
+	maxIssuesFlag = &cli.IntFlag{
+		Name:  "max-issues",
+		Usage: "maximum number of issues of each kind to report (-1 for unlimited)",
+		Value: 100,
+	}
+
 	inputFileArg = &cli.StringArg{
 		Name:      "input-file",
 		UsageText: "(input-file)",
@@ -114,6 +574,12 @@ var (
 		Name:      "output-file",
 		UsageText: "(output-file)",
 	}
+	inputFilesArg = &cli.StringArgs{
+		Name:      "input-files",
+		UsageText: "(input-file)...",
+		Min:       1,
+		Max:       -1,
+	}
 
 	app = &cli.Command{
 		Name:    "nmea-logger",
@@ -131,6 +597,11 @@ var (
 					parityFlag,
 					stopBitsFlag,
 					outputDirFlag,
+					silenceThresholdFlag,
+					exitOnSilenceFlag,
+					zonesFlag,
+					dwellFlag,
+					geofenceOutputFlag,
 				},
 			},
 			{
@@ -145,6 +616,19 @@ var (
 							inputFileArg,
 							outputFileArg,
 						},
+						Flags: []cli.Flag{
+							convertFormatFlag,
+							dedupeWindowFlag,
+							enrichFlag,
+							rawFlag,
+							csvColumnsFlag,
+							timezoneFlag,
+							timeLayoutFlag,
+							etaLayoutFlag,
+							precisionFlag,
+							delimiterFlag,
+							staticLookaheadFlag,
+						},
 					},
 					{
 						Name:   "view",
@@ -157,8 +641,241 @@ var (
 							listenAddrFlag,
 							playbackSpeedFlag,
 							playbackUpdatePeriodFlag,
+							dedupeWindowFlag,
+							rawFlag,
 						},
 					},
+					{
+						Name:   "schema",
+						Usage:  "print the JSON Schema of JSONL output",
+						Action: doAisSchema,
+					},
+					{
+						Name:   "vessels",
+						Usage:  "build a vessel registry with static data change history",
+						Action: doAisVessels,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							mmsiFlag,
+							dedupeWindowFlag,
+							jsonCsvFormatFlag,
+							historyFlag,
+						},
+					},
+					{
+						Name:   "voyages",
+						Usage:  "segment vessel tracks into moored, anchored and underway phases, port calls and voyages",
+						Action: doAisVoyages,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							mmsiFlag,
+							dedupeWindowFlag,
+							portsFlag,
+							portRadiusFlag,
+							stationarySpeedFlag,
+							minDwellFlag,
+							jsonCsvFormatFlag,
+							phasesFlag,
+						},
+					},
+					{
+						Name:   "encounters",
+						Usage:  "detect close-quarters situations between vessels from their closest point of approach (CPA/TCPA)",
+						Action: doAisEncounters,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							mmsiFlag,
+							dedupeWindowFlag,
+							cpaFlag,
+							tcpaFlag,
+							stepFlag,
+							interpolationGapFlag,
+							stationarySpeedFlag,
+							jsonCsvFormatFlag,
+						},
+					},
+					{
+						Name:   "geofence",
+						Usage:  "report vessels entering, leaving and dwelling in zones",
+						Action: doAisGeofence,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							zonesFlag,
+							dwellFlag,
+							mmsiFlag,
+							dedupeWindowFlag,
+						},
+					},
+					{
+						Name:   "anomalies",
+						Usage:  "report implied speeds, duplicate and invalid MMSIs, static data flip-flopping and stale timestamps",
+						Action: doAisAnomalies,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							mmsiFlag,
+							dedupeWindowFlag,
+							maxSpeedFlag,
+							maxStalenessFlag,
+							duplicateWindowFlag,
+							jsonCsvFormatFlag,
+						},
+					},
+					{
+						Name:   "coverage",
+						Usage:  "analyse receiver coverage by bearing, distance and message type",
+						Action: doAisCoverage,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							receiverFlag,
+							sectorsFlag,
+							percentilesFlag,
+							bandWidthFlag,
+							coverageIntervalFlag,
+							coverageFormatFlag,
+							coverageTableFlag,
+						},
+					},
+					{
+						Name:   "simulate",
+						Usage:  "generate synthetic AIS traffic",
+						Action: doAisSimulate,
+						Flags: []cli.Flag{
+							scenarioFlag,
+							randomVesselsFlag,
+							areaFlag,
+							durationFlag,
+							startFlag,
+							seedFlag,
+							tcpFlag,
+							udpFlag,
+						},
+					},
+				},
+			},
+			{
+				Name:  "nmea",
+				Usage: "nmea",
+				Commands: []*cli.Command{
+					{
+						Name:   "decode",
+						Usage:  "decode",
+						Action: doNmeaDecode,
+						Arguments: []cli.Argument{
+							inputFileArg,
+							outputFileArg,
+						},
+					},
+					{
+						Name:   "gpx",
+						Usage:  "export own-ship track as GPX",
+						Action: doNmeaGpx,
+						Arguments: []cli.Argument{
+							inputFilesArg,
+						},
+						Flags: []cli.Flag{
+							outputFlag,
+							maxGapFlag,
+							trackNameFlag,
+						},
+					},
+				},
+			},
+			{
+				Name:   "stats",
+				Usage:  "summarize log content",
+				Action: doStats,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					gapThresholdFlag,
+					outputFormatFlag,
+				},
+			},
+			{
+				Name:   "filter",
+				Usage:  "filter log records",
+				Action: doFilter,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					outputFlag,
+					fromFlag,
+					toFlag,
+					sentenceFlag,
+					talkerFlag,
+					aisTypeFlag,
+					mmsiFlag,
+					bboxFlag,
+					polygonFlag,
+					dropInvalidFlag,
+				},
+			},
+			{
+				Name:   "merge",
+				Usage:  "merge logs in timestamp order",
+				Action: doMerge,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					outputFlag,
+					tagSourceFlag,
+				},
+			},
+			{
+				Name:   "split",
+				Usage:  "split logs by time window or size",
+				Action: doSplit,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					splitOutputDirFlag,
+					intervalFlag,
+					maxSizeFlag,
+					compressionFlag,
+					prefixFlag,
+				},
+			},
+			{
+				Name:   "clock-correct",
+				Usage:  "correct logger clock drift using GNSS and AIS time references",
+				Action: doClockCorrect,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					outputFlag,
+					windowFlag,
+					timeReferenceFlag,
+				},
+			},
+			{
+				Name:   "validate",
+				Usage:  "check log integrity and data quality",
+				Action: doValidate,
+				Arguments: []cli.Argument{
+					inputFilesArg,
+				},
+				Flags: []cli.Flag{
+					gapThresholdFlag,
+					outputFormatFlag,
+					failOnFlag,
+					maxIssuesFlag,
 				},
 			},
 		},
@@ -170,10 +887,47 @@ var (
 )
 
 func main() {
-	err := app.Run(context.Background(), os.Args)
+	err := app.Run(context.Background(), escapeStdioArgs(app, os.Args))
 	if err != nil {
 		log.Error("error",
 			slog.Any("err", err),
 		)
+		os.Exit(1)
+	}
+}
+
+// escapeStdioArgs replaces "-" positional arguments with ioutil.EscapedStdioPath, as the command line parser stops at a
+// "-" argument. Flag values and arguments after "--" are left as is.
+func escapeStdioArgs(root *cli.Command, args []string) []string {
+	escaped := slices.Clone(args)
+	cmd := root
+	flags := slices.Clone(root.Flags)
+	for i := 1; i < len(escaped); i++ {
+		arg := escaped[i]
+		switch {
+		case arg == "--":
+			return escaped
+		case arg == ioutil.StdioPath:
+			escaped[i] = ioutil.EscapedStdioPath
+		case strings.HasPrefix(arg, "-"):
+			name := strings.TrimLeft(arg, "-")
+			if strings.Contains(name, "=") {
+				continue
+			}
+			for _, flag := range flags {
+				docFlag, ok := flag.(cli.DocGenerationFlag)
+				if ok && slices.Contains(flag.Names(), name) && docFlag.TakesValue() {
+					i++
+					break
+				}
+			}
+		default:
+			subcommand := cmd.Command(arg)
+			if subcommand != nil {
+				cmd = subcommand
+				flags = append(flags, subcommand.Flags...)
+			}
+		}
 	}
+	return escaped
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+type mergeInput struct {
+	index              int
+	path               string
+	source             string
+	loggerRecordReader *format.LoggerRecordReader
+	current            *format.LoggerRecord
+}
+
+// next advances to the next record of the input. current is nil once the input is exhausted.
+func (input *mergeInput) next() error {
+	for {
+		loggerRecord, err := input.loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				log.Warn("skipping malformed record",
+					slog.String("file", input.path),
+					slog.Int("line", malformedRecordError.Line),
+				)
+				continue
+			}
+			return err
+		}
+		if (loggerRecord != nil) && (input.source != "") && (loggerRecord.Source == "") {
+			loggerRecord.Source = input.source
+		}
+		input.current = loggerRecord
+		return nil
+	}
+}
+
+// mergeHeap orders inputs by the timestamp of their current record, then by input order.
+type mergeHeap []*mergeInput
+
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].current.Timestamp != h[j].current.Timestamp {
+		return h[i].current.Timestamp < h[j].current.Timestamp
+	}
+	return h[i].index < h[j].index
+}
+
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(*mergeInput))
+}
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+func doMerge(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputFile := cmd.String(outputFlag.Name)
+	tagSource := cmd.Bool(tagSourceFlag.Name)
+
+	f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+	if err != nil {
+		return err
+	}
+	defer func(f io.WriteCloser) {
+		_ = f.Close()
+	}(f)
+	if filepath.Ext(outputFile1) != ".jsonl" {
+		return fmt.Errorf("unsupported file extension")
+	}
+	loggerRecordWriter := format.NewLoggerRecordWriter(f)
+	defer func(loggerRecordWriter *format.LoggerRecordWriter) {
+		_ = loggerRecordWriter.Close()
+	}(loggerRecordWriter)
+
+	var h mergeHeap
+	for i, inputFile := range inputFiles {
+		reader, err := ioutil.OpenFileForReading(inputFile)
+		if err != nil {
+			return err
+		}
+		defer func(reader io.ReadCloser) {
+			_ = reader.Close()
+		}(reader)
+		input := &mergeInput{
+			index:              i,
+			path:               inputFile,
+			loggerRecordReader: format.NewLoggerRecordReader(reader),
+		}
+		if tagSource {
+			input.source = sourceName(inputFile)
+		}
+		err = input.next()
+		if err != nil {
+			return err
+		}
+		if input.current != nil {
+			h = append(h, input)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		input := h[0]
+		err = loggerRecordWriter.WriteLoggerRecord(input.current)
+		if err != nil {
+			return err
+		}
+		err = input.next()
+		if err != nil {
+			return err
+		}
+		if input.current == nil {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+
+	return nil
+}
+
+// sourceName derives a source name from a log file path by removing the directory and any extensions.
+func sourceName(path string) string {
+	name := filepath.Base(path)
+	for _, ext := range []string{".gz", ".bz2", ".xz", ".jsonl", ".log"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+func doNmeaDecode(ctx context.Context, cmd *cli.Command) error {
+	inputFile := cmd.StringArg(inputFileArg.Name)
+	outputFile := cmd.StringArg(outputFileArg.Name)
+	if inputFile == "" {
+		return fmt.Errorf(inputFileArg.Name + " is required")
+	}
+
+	var w io.Writer = os.Stdout
+	if outputFile != "" {
+		f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+		if err != nil {
+			return err
+		}
+		defer func(f io.WriteCloser) {
+			_ = f.Close()
+		}(f)
+
+		ext := filepath.Ext(outputFile1)
+		switch ext {
+		case ".jsonl":
+			w = f
+
+		default:
+			return fmt.Errorf("unsupported file extension")
+		}
+	}
+
+	jsonlWriter := format.NewJsonlWriter(w)
+	defer func(jsonlWriter *format.JsonlWriter) {
+		_ = jsonlWriter.Close()
+	}(jsonlWriter)
+
+	reader, err := ioutil.OpenFileForReading(inputFile)
+	if err != nil {
+		return err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	nmeaRecordReader := format.NewNMEARecordReader(loggerRecordReader)
+	for {
+		nmeaRecord, err := nmeaRecordReader.ReadNMEARecord()
+		if err != nil {
+			return err
+		}
+		if nmeaRecord == nil {
+			break
+		}
+		err = jsonlWriter.WriteRecord(nmeaRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	stats := nmeaRecordReader.Stats()
+	log.Info("decoded NMEA sentences",
+		slog.Int("decoded", stats.Decoded),
+		slog.Any("unsupported", stats.Unsupported),
+		slog.Int("malformed", stats.Malformed),
+		slog.Int("checksumFailures", stats.ChecksumFailures),
+		slog.Int("parseErrors", stats.ParseErrors),
+	)
+
+	return nil
+}
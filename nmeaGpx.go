@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+func doNmeaGpx(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputFile := cmd.String(outputFlag.Name)
+	maxGap := cmd.Duration(maxGapFlag.Name)
+	trackName := cmd.String(trackNameFlag.Name)
+
+	f, outputFile1, err := ioutil.OpenFileForWriting(outputFile)
+	if err != nil {
+		return err
+	}
+	defer func(f io.WriteCloser) {
+		_ = f.Close()
+	}(f)
+	if filepath.Ext(outputFile1) != ".gpx" {
+		return fmt.Errorf("unsupported file extension")
+	}
+	if trackName == "" {
+		trackName = strings.TrimSuffix(filepath.Base(outputFile1), ".gpx")
+	}
+
+	gpxWriter, err := format.NewGpxWriter(f, trackName)
+	if err != nil {
+		return err
+	}
+
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	nmeaRecordReader := format.NewNMEARecordReader(loggerRecordReader)
+	gnssFixReader := format.NewGNSSFixReader(nmeaRecordReader)
+	var lastFix *format.GNSSFix
+	for {
+		fix, err := gnssFixReader.ReadGNSSFix()
+		if err != nil {
+			return err
+		}
+		if fix == nil {
+			break
+		}
+		if !fix.Valid {
+			err = gpxWriter.EndSegment()
+			if err != nil {
+				return err
+			}
+			lastFix = nil
+			continue
+		}
+		if lastFix != nil {
+			gap := fix.Time.Sub(lastFix.Time)
+			if (maxGap > 0) && ((gap > maxGap) || (gap < 0)) {
+				err = gpxWriter.EndSegment()
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = gpxWriter.WriteGNSSFix(fix)
+		if err != nil {
+			return err
+		}
+		lastFix = fix
+	}
+
+	return gpxWriter.Close()
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/BertoldVdb/go-ais/aisnmea"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/urfave/cli/v3"
+)
+
+// simulationStaticInterval is the reporting interval of static and voyage related data (types 5 and 24).
+const simulationStaticInterval = 6 * time.Minute
+
+// simulationMIDs are the MIDs of the MMSIs given to random vessels.
+var simulationMIDs = []uint32{209, 244, 257, 311, 351, 354, 370, 412, 477, 538, 563, 636}
+
+// SimulationScenario describes the vessels to simulate: Vessels, and RandomVessels vessels roaming within Area
+// (min-lon, min-lat, max-lon, max-lat).
+type SimulationScenario struct {
+	Area          []float64          `json:"area,omitempty"`
+	RandomVessels int                `json:"randomVessels,omitempty"`
+	Vessels       []*SimulatedVessel `json:"vessels,omitempty"`
+}
+
+// SimulatedVessel is a vessel following a route of [longitude, latitude] waypoints at Speed knots. At the end of the
+// route, the vessel returns to the first waypoint if Loop is set, and is moored otherwise. Class is "A" (the default)
+// or "B".
+type SimulatedVessel struct {
+	MMSI        uint32       `json:"mmsi"`
+	Class       string       `json:"class,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	CallSign    string       `json:"callSign,omitempty"`
+	IMO         uint32       `json:"imo,omitempty"`
+	ShipType    uint8        `json:"shipType,omitempty"`
+	Length      uint16       `json:"length,omitempty"`
+	Beam        uint8        `json:"beam,omitempty"`
+	Destination string       `json:"destination,omitempty"`
+	Speed       float64      `json:"speed"`
+	Route       [][2]float64 `json:"route"`
+	Loop        bool         `json:"loop,omitempty"`
+}
+
+func loadSimulationScenario(path string) (*SimulationScenario, error) {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenario SimulationScenario
+	err = json.Unmarshal(jsonBytes, &scenario)
+	if err != nil {
+		return nil, err
+	}
+	for _, vessel := range scenario.Vessels {
+		if len(vessel.Route) == 0 {
+			return nil, fmt.Errorf("%s: vessel %d has no route", path, vessel.MMSI)
+		}
+		if (vessel.Class != "") && (vessel.Class != "A") && (vessel.Class != "B") {
+			return nil, fmt.Errorf("%s: vessel %d has an invalid class %s", path, vessel.MMSI, vessel.Class)
+		}
+		if vessel.Loop && (routeLength(vessel.Route) == 0) {
+			return nil, fmt.Errorf("%s: vessel %d has a looped route of zero length", path, vessel.MMSI)
+		}
+	}
+	return &scenario, nil
+}
+
+// routeLength returns the length of a route, in metres.
+func routeLength(route [][2]float64) float64 {
+	length := 0.0
+	for i := 1; i < len(route); i++ {
+		length += geo.Distance(geo.Point{Longitude: route[i-1][0], Latitude: route[i-1][1]},
+			geo.Point{Longitude: route[i][0], Latitude: route[i][1]})
+	}
+	return length
+}
+
+// simulationVessel is the state of a simulated vessel. eta is the ETA reported in its static data, fixed for the whole
+// simulation.
+type simulationVessel struct {
+	config       *SimulatedVessel
+	point        geo.Point
+	sog          float64
+	cog          float64
+	moored       bool
+	waypoint     int
+	area         *geo.BoundingBox
+	lastMove     time.Time
+	nextPosition time.Time
+	nextStatic   time.Time
+	eta          time.Time
+	channel      byte
+}
+
+// move advances the vessel along its route, or towards a random waypoint within its area, up to t.
+func (vessel *simulationVessel) move(t time.Time, random *rand.Rand) {
+	remaining := vessel.config.Speed * geo.MetresPerNauticalMile * t.Sub(vessel.lastMove).Hours()
+	vessel.lastMove = t
+	for !vessel.moored && (remaining > 0) {
+		target := vessel.target()
+		distance := geo.Distance(vessel.point, target)
+		if distance > remaining {
+			vessel.cog = geo.Bearing(vessel.point, target)
+			vessel.point = geo.Destination(vessel.point, vessel.cog, remaining)
+			return
+		}
+		vessel.point = target
+		remaining -= distance
+		vessel.nextWaypoint(random)
+		// A zero-length leg uses up no distance, so stop rather than loop until the next move
+		if distance == 0 {
+			break
+		}
+	}
+}
+
+func (vessel *simulationVessel) target() geo.Point {
+	waypoint := vessel.config.Route[vessel.waypoint]
+	return geo.Point{
+		Longitude: waypoint[0],
+		Latitude:  waypoint[1],
+	}
+}
+
+func (vessel *simulationVessel) nextWaypoint(random *rand.Rand) {
+	switch {
+	case vessel.area != nil:
+		vessel.config.Route[vessel.waypoint] = randomPoint(random, *vessel.area)
+	case vessel.waypoint+1 < len(vessel.config.Route):
+		vessel.waypoint++
+	case vessel.config.Loop && (len(vessel.config.Route) > 1):
+		vessel.waypoint = 0
+	default:
+		vessel.moored = true
+		vessel.sog = 0
+	}
+}
+
+// positionInterval returns the reporting interval of the vessel (ITU-R M.1371 Table 1, ignoring course changes; Class
+// B vessels are CS units).
+func (vessel *simulationVessel) positionInterval() time.Duration {
+	switch {
+	case vessel.config.Class == "B" && (vessel.sog <= 2):
+		return 3 * time.Minute
+	case vessel.config.Class == "B":
+		return 30 * time.Second
+	case vessel.moored || (vessel.sog <= 3):
+		return 3 * time.Minute
+	case vessel.sog <= 14:
+		return 10 * time.Second
+	case vessel.sog <= 23:
+		return 6 * time.Second
+	default:
+		return 2 * time.Second
+	}
+}
+
+// packets returns the packets reported by the vessel at t: a position report, followed by its static data if due.
+func (vessel *simulationVessel) packets(t time.Time) []ais.Packet {
+	config := vessel.config
+	heading := uint16(math.Round(vessel.cog)) % 360
+	var packets []ais.Packet
+	if config.Class == "B" {
+		packets = append(packets, ais.StandardClassBPositionReport{
+			Header:      ais.Header{MessageID: 18, UserID: config.MMSI},
+			Valid:       true,
+			Sog:         ais.Field10(vessel.sog),
+			Longitude:   ais.FieldLatLonFine(vessel.point.Longitude),
+			Latitude:    ais.FieldLatLonFine(vessel.point.Latitude),
+			Cog:         ais.Field10(vessel.cog),
+			TrueHeading: heading,
+			Timestamp:   uint8(t.Second()),
+			ClassBUnit:  true,
+		})
+	} else {
+		navigationalStatus := uint8(0)
+		if vessel.moored {
+			navigationalStatus = 5
+		}
+		packets = append(packets, ais.PositionReport{
+			Header:             ais.Header{MessageID: 1, UserID: config.MMSI},
+			Valid:              true,
+			NavigationalStatus: navigationalStatus,
+			RateOfTurn:         0,
+			Sog:                ais.Field10(vessel.sog),
+			PositionAccuracy:   true,
+			Longitude:          ais.FieldLatLonFine(vessel.point.Longitude),
+			Latitude:           ais.FieldLatLonFine(vessel.point.Latitude),
+			Cog:                ais.Field10(vessel.cog),
+			TrueHeading:        heading,
+			Timestamp:          uint8(t.Second()),
+		})
+	}
+	if t.Before(vessel.nextStatic) {
+		return packets
+	}
+	vessel.nextStatic = t.Add(simulationStaticInterval)
+
+	dimension := ais.FieldDimension{
+		A: config.Length / 2,
+		B: config.Length - config.Length/2,
+		C: config.Beam / 2,
+		D: config.Beam - config.Beam/2,
+	}
+	if config.Class == "B" {
+		return append(packets,
+			ais.StaticDataReport{
+				Header:     ais.Header{MessageID: 24, UserID: config.MMSI},
+				Valid:      true,
+				PartNumber: false,
+				ReportA: ais.StaticDataReportA{
+					Valid: true,
+					Name:  config.Name,
+				},
+			},
+			ais.StaticDataReport{
+				Header:     ais.Header{MessageID: 24, UserID: config.MMSI},
+				Valid:      true,
+				PartNumber: true,
+				ReportB: ais.StaticDataReportB{
+					Valid:     true,
+					ShipType:  config.ShipType,
+					CallSign:  config.CallSign,
+					Dimension: dimension,
+					FixType:   1,
+				},
+			},
+		)
+	}
+	eta := vessel.eta
+	return append(packets, ais.ShipStaticData{
+		Header:               ais.Header{MessageID: 5, UserID: config.MMSI},
+		Valid:                true,
+		ImoNumber:            config.IMO,
+		CallSign:             config.CallSign,
+		Name:                 config.Name,
+		Type:                 config.ShipType,
+		Dimension:            dimension,
+		FixType:              1,
+		Eta:                  ais.FieldETA{Month: uint8(eta.Month()), Day: uint8(eta.Day()), Hour: uint8(eta.Hour()), Minute: uint8(eta.Minute())},
+		Destination:          config.Destination,
+		MaximumStaticDraught: 5,
+	})
+}
+
+func randomPoint(random *rand.Rand, area geo.BoundingBox) [2]float64 {
+	return [2]float64{
+		area.MinLongitude + random.Float64()*(area.MaxLongitude-area.MinLongitude),
+		area.MinLatitude + random.Float64()*(area.MaxLatitude-area.MinLatitude),
+	}
+}
+
+// randomSimulationVessel returns a vessel roaming within area.
+func randomSimulationVessel(random *rand.Rand, i int, area geo.BoundingBox) *SimulatedVessel {
+	mid := simulationMIDs[random.Intn(len(simulationMIDs))]
+	vessel := &SimulatedVessel{
+		MMSI:        mid*1000000 + uint32(random.Intn(1000000)),
+		Class:       "A",
+		Name:        fmt.Sprintf("SIM VESSEL %d", i+1),
+		CallSign:    fmt.Sprintf("SIM%d", i+1),
+		ShipType:    []uint8{30, 52, 60, 70, 80}[random.Intn(5)],
+		Length:      uint16(20 + random.Intn(280)),
+		Destination: "RANDOM",
+		Speed:       math.Round((4+random.Float64()*16)*10) / 10,
+		Route:       [][2]float64{randomPoint(random, area), randomPoint(random, area)},
+	}
+	vessel.Beam = uint8(max(vessel.Length/7, 4))
+	if random.Intn(4) == 0 {
+		vessel.Class = "B"
+		vessel.IMO = 0
+		vessel.Length = min(vessel.Length, 30)
+		vessel.Beam = uint8(max(vessel.Length/4, 3))
+		vessel.Speed = math.Round((3+random.Float64()*22)*10) / 10
+	} else {
+		vessel.IMO = uint32(9000000 + random.Intn(1000000))
+	}
+	return vessel
+}
+
+// simulationQueue orders vessels by the time of their next position report.
+type simulationQueue []*simulationVessel
+
+func (queue simulationQueue) Len() int {
+	return len(queue)
+}
+
+func (queue simulationQueue) Less(i, j int) bool {
+	return queue[i].nextPosition.Before(queue[j].nextPosition)
+}
+
+func (queue simulationQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+}
+
+func (queue *simulationQueue) Push(x any) {
+	*queue = append(*queue, x.(*simulationVessel))
+}
+
+func (queue *simulationQueue) Pop() any {
+	old := *queue
+	vessel := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return vessel
+}
+
+// simulationOutput is where simulated sentences are written to.
+type simulationOutput interface {
+	write(record *format.LoggerRecord) error
+	Close() error
+}
+
+// jsonlSimulationOutput writes logger records.
+type jsonlSimulationOutput struct {
+	writer *format.JsonlWriter
+}
+
+func (output *jsonlSimulationOutput) write(record *format.LoggerRecord) error {
+	return output.writer.WriteRecord(record)
+}
+
+func (output *jsonlSimulationOutput) Close() error {
+	return nil
+}
+
+// udpSimulationOutput sends each sentence as a datagram.
+type udpSimulationOutput struct {
+	conn net.Conn
+}
+
+func (output *udpSimulationOutput) write(record *format.LoggerRecord) error {
+	_, err := output.conn.Write([]byte(record.NMEA + "\r\n"))
+	return err
+}
+
+func (output *udpSimulationOutput) Close() error {
+	return output.conn.Close()
+}
+
+// tcpSimulationOutput sends the sentences to every connected client.
+type tcpSimulationOutput struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    map[net.Conn]bool
+}
+
+func newTcpSimulationOutput(addr string) (*tcpSimulationOutput, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	output := &tcpSimulationOutput{
+		listener: listener,
+		conns:    make(map[net.Conn]bool),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			log.Info("client connected",
+				slog.String("remoteAddr", conn.RemoteAddr().String()),
+			)
+			output.mutex.Lock()
+			output.conns[conn] = true
+			output.mutex.Unlock()
+		}
+	}()
+	return output, nil
+}
+
+func (output *tcpSimulationOutput) write(record *format.LoggerRecord) error {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	for conn := range output.conns {
+		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := conn.Write([]byte(record.NMEA + "\r\n"))
+		if err != nil {
+			log.Info("client disconnected",
+				slog.String("remoteAddr", conn.RemoteAddr().String()),
+			)
+			_ = conn.Close()
+			delete(output.conns, conn)
+		}
+	}
+	return nil
+}
+
+func (output *tcpSimulationOutput) Close() error {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	for conn := range output.conns {
+		_ = conn.Close()
+	}
+	return output.listener.Close()
+}
+
+func doAisSimulate(ctx context.Context, cmd *cli.Command) error {
+	scenarioFile := cmd.String(scenarioFlag.Name)
+	randomVessels := cmd.Int(randomVesselsFlag.Name)
+	area := cmd.Float64Slice(areaFlag.Name)
+	duration := cmd.Duration(durationFlag.Name)
+	start := cmd.Timestamp(startFlag.Name)
+	seed := cmd.Int64(seedFlag.Name)
+	tcpAddr := cmd.String(tcpFlag.Name)
+	udpAddr := cmd.String(udpFlag.Name)
+
+	scenario := &SimulationScenario{}
+	if scenarioFile != "" {
+		var err error
+		scenario, err = loadSimulationScenario(scenarioFile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(area) > 0 {
+		scenario.Area = area
+	}
+	if randomVessels > 0 {
+		scenario.RandomVessels = randomVessels
+	}
+	if (scenario.RandomVessels > 0) && (len(scenario.Area) != 4) {
+		return fmt.Errorf("random vessels need an area (min-lon,min-lat,max-lon,max-lat)")
+	}
+	if (len(scenario.Area) == 4) && ((scenario.Area[2] <= scenario.Area[0]) || (scenario.Area[3] <= scenario.Area[1])) {
+		return fmt.Errorf("area must have a positive width and height (min-lon,min-lat,max-lon,max-lat)")
+	}
+	if (len(scenario.Vessels) == 0) && (scenario.RandomVessels == 0) {
+		return fmt.Errorf("no vessels to simulate")
+	}
+
+	realtime := (tcpAddr != "") || (udpAddr != "")
+	if start.IsZero() || realtime {
+		start = time.Now().UTC()
+	}
+	if (duration <= 0) && !realtime {
+		return fmt.Errorf("%s must be positive", durationFlag.Name)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+
+	var output simulationOutput
+	switch {
+	case tcpAddr != "":
+		tcpOutput, err := newTcpSimulationOutput(tcpAddr)
+		if err != nil {
+			return err
+		}
+		output = tcpOutput
+	case udpAddr != "":
+		conn, err := net.Dial("udp", udpAddr)
+		if err != nil {
+			return err
+		}
+		output = &udpSimulationOutput{
+			conn: conn,
+		}
+	default:
+		output = &jsonlSimulationOutput{
+			writer: format.NewJsonlWriter(os.Stdout),
+		}
+	}
+	defer func(output io.Closer) {
+		_ = output.Close()
+	}(output)
+
+	configs := scenario.Vessels
+	var areaBox geo.BoundingBox
+	if len(scenario.Area) == 4 {
+		areaBox = geo.BoundingBox{
+			MinLongitude: scenario.Area[0],
+			MinLatitude:  scenario.Area[1],
+			MaxLongitude: scenario.Area[2],
+			MaxLatitude:  scenario.Area[3],
+		}
+	}
+	for i := 0; i < scenario.RandomVessels; i++ {
+		configs = append(configs, randomSimulationVessel(random, i, areaBox))
+	}
+	queue := &simulationQueue{}
+	for i, config := range configs {
+		config.Name = strings.ToUpper(config.Name)
+		config.CallSign = strings.ToUpper(config.CallSign)
+		config.Destination = strings.ToUpper(config.Destination)
+		vessel := &simulationVessel{
+			config: config,
+			point: geo.Point{
+				Longitude: config.Route[0][0],
+				Latitude:  config.Route[0][1],
+			},
+			sog:      config.Speed,
+			lastMove: start,
+			eta:      start.Add(24 * time.Hour).UTC(),
+			channel:  1,
+		}
+		if i >= len(scenario.Vessels) {
+			vessel.area = &areaBox
+		}
+		if len(config.Route) > 1 {
+			vessel.waypoint = 1
+		}
+		if (len(config.Route) == 1) || (config.Speed <= 0) {
+			vessel.moored = true
+			vessel.sog = 0
+		}
+		vessel.cog = geo.Bearing(vessel.point, vessel.target())
+		// Spread the first reports over the reporting interval, but report every vessel within the first 10 seconds
+		offset := time.Duration(random.Int63n(int64(min(vessel.positionInterval(), 10*time.Second))))
+		vessel.nextPosition = start.Add(offset)
+		vessel.nextStatic = vessel.nextPosition
+		heap.Push(queue, vessel)
+	}
+
+	nmeaCodec := aisnmea.NMEACodecNew(ais.CodecNew(false, false))
+	end := start.Add(duration)
+	for {
+		vessel := (*queue)[0]
+		t := vessel.nextPosition
+		if (duration > 0) && !t.Before(end) {
+			return nil
+		}
+		if realtime {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(t)):
+			}
+		}
+
+		vessel.move(t, random)
+		for _, packet := range vessel.packets(t) {
+			sentences := nmeaCodec.EncodeSentence(aisnmea.VdmPacket{
+				Channel:     vessel.channel,
+				TalkerID:    "AI",
+				MessageType: "VDM",
+				Packet:      packet,
+			})
+			if sentences == nil {
+				return fmt.Errorf("error encoding type %d message of %d", packet.GetHeader().MessageID,
+					packet.GetHeader().UserID)
+			}
+			for _, sentence := range sentences {
+				err := output.write(&format.LoggerRecord{
+					Timestamp: t.UnixMilli(),
+					NMEA:      sentence,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			vessel.channel = 3 - vessel.channel
+		}
+		vessel.nextPosition = t.Add(vessel.positionInterval())
+		heap.Fix(queue, 0)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/geo"
+)
+
+func TestSimulationVesselMove(t *testing.T) {
+	start := time.Unix(0, 0).UTC()
+	tests := []struct {
+		name  string
+		route [][2]float64
+		loop  bool
+		area  *geo.BoundingBox
+		want  geo.Point
+	}{
+		{"zero-length loop", [][2]float64{{103.8, 1.2}, {103.8, 1.2}}, true, nil, geo.Point{Longitude: 103.8, Latitude: 1.2}},
+		{"zero-area roaming", [][2]float64{{103.8, 1.2}, {103.8, 1.2}}, false,
+			&geo.BoundingBox{MinLongitude: 103.8, MinLatitude: 1.2, MaxLongitude: 103.8, MaxLatitude: 1.2},
+			geo.Point{Longitude: 103.8, Latitude: 1.2}},
+		{"end of route", [][2]float64{{103.8, 1.2}, {103.81, 1.2}}, false, nil, geo.Point{Longitude: 103.81, Latitude: 1.2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vessel := &simulationVessel{
+				config: &SimulatedVessel{
+					Speed: 10,
+					Route: test.route,
+					Loop:  test.loop,
+				},
+				point:    geo.Point{Longitude: test.route[0][0], Latitude: test.route[0][1]},
+				waypoint: 1,
+				area:     test.area,
+				lastMove: start,
+			}
+			vessel.move(start.Add(time.Hour), rand.New(rand.NewSource(1)))
+			if geo.Distance(vessel.point, test.want) > 1 {
+				t.Errorf("point = %v, want %v", vessel.point, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadSimulationScenarioZeroLengthLoop(t *testing.T) {
+	path := t.TempDir() + "/scenario.json"
+	err := os.WriteFile(path, []byte(`{"vessels":[{"mmsi":563000001,"speed":10,"loop":true,"route":[[103.8,1.2],[103.8,1.2]]}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadSimulationScenario(path)
+	if err == nil {
+		t.Errorf("loadSimulationScenario() error = nil, want error")
+	}
+}
+
+func TestSimulationVesselEta(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	vessel := &simulationVessel{
+		config: &SimulatedVessel{
+			MMSI:  563000001,
+			Route: [][2]float64{{103.8, 1.2}},
+		},
+		moored: true,
+		eta:    start.Add(24 * time.Hour),
+	}
+	want := ais.FieldETA{Month: 1, Day: 2, Hour: 0, Minute: 0}
+	for _, t0 := range []time.Time{start, start.Add(simulationStaticInterval), start.Add(time.Hour)} {
+		vessel.nextStatic = t0
+		var eta *ais.FieldETA
+		for _, packet := range vessel.packets(t0) {
+			shipStaticData, ok := packet.(ais.ShipStaticData)
+			if ok {
+				eta = &shipStaticData.Eta
+			}
+		}
+		if (eta == nil) || (*eta != want) {
+			t.Errorf("ETA at %s = %v, want %v", t0, eta, want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (writer *countingWriter) Write(buf []byte) (int, error) {
+	n, err := writer.w.Write(buf)
+	writer.n += int64(n)
+	return n, err
+}
+
+// splitChunk is an output file of the split command. It is written under a temporary name, and renamed after the time
+// range it covers when closed.
+type splitChunk struct {
+	tempPath           string
+	f                  io.WriteCloser
+	countingWriter     *countingWriter
+	loggerRecordWriter *format.LoggerRecordWriter
+	window             int64
+	firstTimestamp     int64
+	lastTimestamp      int64
+}
+
+type splitter struct {
+	outputDir   string
+	prefix      string
+	extension   string
+	interval    time.Duration
+	maxSize     int64
+	chunk       *splitChunk
+	chunkNumber int
+}
+
+func (s *splitter) write(loggerRecord *format.LoggerRecord) error {
+	var window int64
+	if s.interval > 0 {
+		window = loggerRecord.Timestamp / s.interval.Milliseconds()
+	}
+	if s.chunk != nil {
+		if (s.interval > 0) && (window != s.chunk.window) {
+			err := s.closeChunk()
+			if err != nil {
+				return err
+			}
+		} else if (s.maxSize > 0) && (s.chunk.countingWriter.n >= s.maxSize) {
+			err := s.closeChunk()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if s.chunk == nil {
+		s.chunkNumber++
+		tempPath := filepath.Join(s.outputDir, fmt.Sprintf(".%s-%d.partial%s", s.prefix, s.chunkNumber, s.extension))
+		f, _, err := ioutil.OpenFileForWriting(tempPath)
+		if err != nil {
+			return err
+		}
+		countingWriter := &countingWriter{
+			w: f,
+		}
+		s.chunk = &splitChunk{
+			tempPath:           tempPath,
+			f:                  f,
+			countingWriter:     countingWriter,
+			loggerRecordWriter: format.NewLoggerRecordWriter(countingWriter),
+			window:             window,
+			firstTimestamp:     loggerRecord.Timestamp,
+			lastTimestamp:      loggerRecord.Timestamp,
+		}
+	}
+
+	s.chunk.firstTimestamp = min(s.chunk.firstTimestamp, loggerRecord.Timestamp)
+	s.chunk.lastTimestamp = max(s.chunk.lastTimestamp, loggerRecord.Timestamp)
+	return s.chunk.loggerRecordWriter.WriteLoggerRecord(loggerRecord)
+}
+
+func (s *splitter) closeChunk() error {
+	chunk := s.chunk
+	if chunk == nil {
+		return nil
+	}
+	s.chunk = nil
+
+	// A chunk that fails to close, e.g. as the compressor cannot be flushed, is left under its temporary name
+	err := chunk.loggerRecordWriter.Close()
+	closeErr := chunk.f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", chunk.tempPath, err)
+	}
+
+	const layout = "20060102T150405Z"
+	name := fmt.Sprintf("%s_%s_%s", s.prefix,
+		time.UnixMilli(chunk.firstTimestamp).UTC().Format(layout),
+		time.UnixMilli(chunk.lastTimestamp).UTC().Format(layout),
+	)
+	path := filepath.Join(s.outputDir, name+s.extension)
+	for i := 1; ; i++ {
+		_, err = os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(s.outputDir, fmt.Sprintf("%s-%d%s", name, i, s.extension))
+	}
+	err = os.Rename(chunk.tempPath, path)
+	if err != nil {
+		return err
+	}
+	log.Info("wrote chunk",
+		slog.String("file", path),
+	)
+	return nil
+}
+
+func doSplit(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	outputDir := cmd.String(splitOutputDirFlag.Name)
+	interval := cmd.Duration(intervalFlag.Name)
+	maxSize0 := cmd.String(maxSizeFlag.Name)
+	compression := cmd.String(compressionFlag.Name)
+	prefix := cmd.String(prefixFlag.Name)
+
+	var maxSize int64
+	if maxSize0 != "" {
+		var err error
+		maxSize, err = parseByteSize(maxSize0)
+		if err != nil {
+			return err
+		}
+	}
+	if (interval <= 0) && (maxSize <= 0) {
+		return fmt.Errorf("either %s or %s is required", intervalFlag.Name, maxSizeFlag.Name)
+	}
+
+	extension := ".jsonl"
+	if compression != "none" {
+		extension += "." + compression
+	}
+
+	err := os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	s := &splitter{
+		outputDir: outputDir,
+		prefix:    prefix,
+		extension: extension,
+		interval:  interval,
+		maxSize:   maxSize,
+	}
+	defer func(s *splitter) {
+		if s.chunk != nil {
+			_ = s.chunk.f.Close()
+			_ = os.Remove(s.chunk.tempPath)
+		}
+	}(s)
+
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				log.Warn("skipping malformed record",
+					slog.Int("line", malformedRecordError.Line),
+				)
+				continue
+			}
+			return err
+		}
+		if loggerRecord == nil {
+			break
+		}
+		err = s.write(loggerRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.closeChunk()
+}
+
+// parseByteSize parses a size such as "500M" or "2G" (binary multiples).
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size")
+	}
+	return int64(n * float64(multiplier)), nil
+}
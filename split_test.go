@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+)
+
+func TestIntervalFlagValidator(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		wantErr  bool
+	}{
+		{-time.Hour, true},
+		{0, true},
+		{time.Nanosecond, true},
+		{500 * time.Microsecond, true},
+		{time.Millisecond, false},
+		{time.Hour, false},
+	}
+	for _, test := range tests {
+		t.Run(test.interval.String(), func(t *testing.T) {
+			err := intervalFlag.Validator(test.interval)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validator(%s) error = %v, want error %t", test.interval, err, test.wantErr)
+			}
+		})
+	}
+}
+
+type failingWriteCloser struct {
+	err error
+}
+
+func (writer failingWriteCloser) Write(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func (writer failingWriteCloser) Close() error {
+	return writer.err
+}
+
+func TestSplitterCloseChunkError(t *testing.T) {
+	outputDir := t.TempDir()
+	tempPath := filepath.Join(outputDir, ".nmea-1.partial.jsonl.gz")
+	err := os.WriteFile(tempPath, nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errFlush := errors.New("no space left on device")
+	countingWriter := &countingWriter{
+		w: failingWriteCloser{},
+	}
+	s := &splitter{
+		outputDir: outputDir,
+		prefix:    "nmea",
+		extension: ".jsonl.gz",
+		chunk: &splitChunk{
+			tempPath:           tempPath,
+			f:                  failingWriteCloser{err: errFlush},
+			countingWriter:     countingWriter,
+			loggerRecordWriter: format.NewLoggerRecordWriter(countingWriter),
+		},
+	}
+	err = s.closeChunk()
+	if !errors.Is(err, errFlush) {
+		t.Errorf("closeChunk() error = %v, want %v", err, errFlush)
+	}
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(entries) != 1) || (entries[0].Name() != filepath.Base(tempPath)) {
+		t.Errorf("output directory holds %v, want only the temporary file", entries)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+type LogStats struct {
+	Records                int            `json:"records"`
+	MalformedRecords       int            `json:"malformedRecords"`
+	MalformedSentences     int            `json:"malformedSentences"`
+	ChecksumFailures       int            `json:"checksumFailures"`
+	Talkers                map[string]int `json:"talkers"`
+	SentenceTypes          map[string]int `json:"sentenceTypes"`
+	AISMessageTypes        map[string]int `json:"aisMessageTypes"`
+	UndecodableAISMessages int            `json:"undecodableAisMessages"`
+	UniqueMMSIs            int            `json:"uniqueMmsis"`
+	FirstTimestamp         *time.Time     `json:"firstTimestamp,omitempty"`
+	LastTimestamp          *time.Time     `json:"lastTimestamp,omitempty"`
+	TimeSpan               string         `json:"timeSpan"`
+	Gaps                   []LogGap       `json:"gaps"`
+	Hourly                 []HourlyCount  `json:"hourly"`
+}
+
+type LogGap struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+}
+
+type HourlyCount struct {
+	Hour  time.Time `json:"hour"`
+	Count int       `json:"count"`
+}
+
+func doStats(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	gapThreshold := cmd.Duration(gapThresholdFlag.Name)
+	outputFormat := cmd.String(outputFormatFlag.Name)
+
+	stats := LogStats{
+		Talkers:         make(map[string]int),
+		SentenceTypes:   make(map[string]int),
+		AISMessageTypes: make(map[string]int),
+		Gaps:            []LogGap{},
+		Hourly:          []HourlyCount{},
+	}
+	mmsiMap := make(map[uint32]struct{})
+	hourlyMap := make(map[int64]int)
+	var firstTimestamp int64
+	var lastTimestamp int64
+	var previousTimestamp int64
+
+	reader := ioutil.OpenFilesForReading(inputFiles)
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	aisDecoder := format.NewAISDecoder()
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				stats.MalformedRecords++
+				continue
+			}
+			return err
+		}
+		if loggerRecord == nil {
+			break
+		}
+
+		stats.Records++
+		if (stats.Records == 1) || (loggerRecord.Timestamp < firstTimestamp) {
+			firstTimestamp = loggerRecord.Timestamp
+		}
+		if (stats.Records == 1) || (loggerRecord.Timestamp > lastTimestamp) {
+			lastTimestamp = loggerRecord.Timestamp
+		}
+		if (stats.Records > 1) && (gapThreshold > 0) {
+			gap := time.Duration(loggerRecord.Timestamp-previousTimestamp) * time.Millisecond
+			if gap > gapThreshold {
+				stats.Gaps = append(stats.Gaps, LogGap{
+					Start:    time.UnixMilli(previousTimestamp).UTC(),
+					End:      time.UnixMilli(loggerRecord.Timestamp).UTC(),
+					Duration: gap.String(),
+				})
+			}
+		}
+		previousTimestamp = loggerRecord.Timestamp
+		hourlyMap[time.UnixMilli(loggerRecord.Timestamp).UTC().Truncate(time.Hour).UnixMilli()]++
+
+		sentenceInfo, err := format.ParseSentenceInfo(loggerRecord.NMEA)
+		if err != nil {
+			stats.MalformedSentences++
+			continue
+		}
+		if !sentenceInfo.ChecksumValid {
+			stats.ChecksumFailures++
+			continue
+		}
+		stats.Talkers[sentenceInfo.Talker]++
+		stats.SentenceTypes[sentenceInfo.Type]++
+
+		if !sentenceInfo.Encapsulated {
+			continue
+		}
+		vdmPacket, err := aisDecoder.ParseSentence(loggerRecord.NMEA)
+		if (err != nil) || (vdmPacket == nil) {
+			continue
+		}
+		if vdmPacket.Packet == nil {
+			stats.UndecodableAISMessages++
+			continue
+		}
+		header := vdmPacket.Packet.GetHeader()
+		stats.AISMessageTypes[strconv.Itoa(int(header.MessageID))]++
+		mmsiMap[header.UserID] = struct{}{}
+	}
+
+	stats.UniqueMMSIs = len(mmsiMap)
+	if stats.Records > 0 {
+		first := time.UnixMilli(firstTimestamp).UTC()
+		last := time.UnixMilli(lastTimestamp).UTC()
+		stats.FirstTimestamp = &first
+		stats.LastTimestamp = &last
+		stats.TimeSpan = last.Sub(first).String()
+	}
+	for hour, count := range hourlyMap {
+		stats.Hourly = append(stats.Hourly, HourlyCount{
+			Hour:  time.UnixMilli(hour).UTC(),
+			Count: count,
+		})
+	}
+	sort.Slice(stats.Hourly, func(i, j int) bool {
+		return stats.Hourly[i].Hour.Before(stats.Hourly[j].Hour)
+	})
+
+	switch outputFormat {
+	case "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		return jsonEncoder.Encode(stats)
+
+	default:
+		return printLogStats(os.Stdout, &stats)
+	}
+}
+
+func printLogStats(w io.Writer, stats *LogStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(tw, "Records:\t%d\n", stats.Records)
+	_, _ = fmt.Fprintf(tw, "Malformed records:\t%d\n", stats.MalformedRecords)
+	_, _ = fmt.Fprintf(tw, "Malformed sentences:\t%d\n", stats.MalformedSentences)
+	_, _ = fmt.Fprintf(tw, "Checksum failures:\t%d\n", stats.ChecksumFailures)
+	_, _ = fmt.Fprintf(tw, "Undecodable AIS messages:\t%d\n", stats.UndecodableAISMessages)
+	_, _ = fmt.Fprintf(tw, "Unique MMSIs:\t%d\n", stats.UniqueMMSIs)
+	if stats.FirstTimestamp != nil {
+		_, _ = fmt.Fprintf(tw, "First timestamp:\t%s\n", stats.FirstTimestamp.Format(time.RFC3339Nano))
+		_, _ = fmt.Fprintf(tw, "Last timestamp:\t%s\n", stats.LastTimestamp.Format(time.RFC3339Nano))
+		_, _ = fmt.Fprintf(tw, "Time span:\t%s\n", stats.TimeSpan)
+	}
+
+	printCounts := func(title string, counts map[string]int) {
+		_, _ = fmt.Fprintf(tw, "\n%s:\n", title)
+		var keys []string
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts[keys[i]] != counts[keys[j]] {
+				return counts[keys[i]] > counts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(tw, "  %s\t%d\n", key, counts[key])
+		}
+	}
+	printCounts("Talkers", stats.Talkers)
+	printCounts("Sentence types", stats.SentenceTypes)
+	printCounts("AIS message types", stats.AISMessageTypes)
+
+	_, _ = fmt.Fprintf(tw, "\nGaps:\n")
+	for _, gap := range stats.Gaps {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\n", gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339), gap.Duration)
+	}
+
+	_, _ = fmt.Fprintf(tw, "\nHourly:\n")
+	for _, hourlyCount := range stats.Hourly {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\n", hourlyCount.Hour.Format("2006-01-02 15:00"), hourlyCount.Count)
+	}
+
+	return tw.Flush()
+}
@@ -1,4 +1,12 @@
-export type AISRecord = PositionReportRecord | ShipStaticDataRecord;
+export type AISRecord = PositionReportRecord
+    | ShipStaticDataRecord
+    | StandardClassBPositionReportRecord
+    | ExtendedClassBPositionReportRecord
+    | StaticDataReportRecord
+    | BaseStationReportRecord
+    | AidsToNavigationReportRecord
+    | StandardSearchAndRescueAircraftReportRecord
+    | LongRangeAisBroadcastMessageRecord;
 
 export interface PositionReportRecord {
     type: 'positionReport';
@@ -12,6 +20,48 @@ export interface ShipStaticDataRecord {
     shipStaticData: ShipStaticData;
 }
 
+export interface StandardClassBPositionReportRecord {
+    type: 'standardClassBPositionReport';
+    t: number;
+    standardClassBPositionReport: StandardClassBPositionReport;
+}
+
+export interface ExtendedClassBPositionReportRecord {
+    type: 'extendedClassBPositionReport';
+    t: number;
+    extendedClassBPositionReport: ExtendedClassBPositionReport;
+}
+
+export interface StaticDataReportRecord {
+    type: 'staticDataReport';
+    t: number;
+    staticDataReport: StaticDataReport;
+}
+
+export interface BaseStationReportRecord {
+    type: 'baseStationReport';
+    t: number;
+    baseStationReport: BaseStationReport;
+}
+
+export interface AidsToNavigationReportRecord {
+    type: 'aidsToNavigationReport';
+    t: number;
+    aidsToNavigationReport: AidsToNavigationReport;
+}
+
+export interface StandardSearchAndRescueAircraftReportRecord {
+    type: 'standardSearchAndRescueAircraftReport';
+    t: number;
+    standardSearchAndRescueAircraftReport: StandardSearchAndRescueAircraftReport;
+}
+
+export interface LongRangeAisBroadcastMessageRecord {
+    type: 'longRangeAisBroadcastMessage';
+    t: number;
+    longRangeAisBroadcastMessage: LongRangeAisBroadcastMessage;
+}
+
 export interface ShipStaticData {
     UserID: number;
     ImoNumber: number;
@@ -41,18 +91,140 @@ export interface ETA {
     Minute: number;
 }
 
+// Fields holding an AIS "not available" value (e.g. latitude 91 or heading 511) are null, unless the server was
+// started with --raw.
 export interface PositionReport {
     UserID: number;
     NavigationalStatus: number;
-    RateOfTurn: number;
-    Sog: number;
+    RateOfTurn: number | null;
+    Sog: number | null;
     PositionAccuracy: boolean;
-    Longitude: number;
-    Latitude: number;
-    Cog: number;
-    TrueHeading: number;
+    Longitude: number | null;
+    Latitude: number | null;
+    Cog: number | null;
+    TrueHeading: number | null;
     Timestamp: number;
     SpecialManoeuvreIndicator: number;
     Spare: number;
     Raim: boolean;
 }
+
+export interface StandardClassBPositionReport {
+    UserID: number;
+    Sog: number | null;
+    PositionAccuracy: boolean;
+    Longitude: number | null;
+    Latitude: number | null;
+    Cog: number | null;
+    TrueHeading: number | null;
+    Timestamp: number;
+    ClassBUnit: boolean;
+    ClassBDisplay: boolean;
+    ClassBDsc: boolean;
+    ClassBBand: boolean;
+    ClassBMsg22: boolean;
+    AssignedMode: boolean;
+    Raim: boolean;
+}
+
+export interface ExtendedClassBPositionReport {
+    UserID: number;
+    Sog: number | null;
+    PositionAccuracy: boolean;
+    Longitude: number | null;
+    Latitude: number | null;
+    Cog: number | null;
+    TrueHeading: number | null;
+    Timestamp: number;
+    Name: string;
+    Type: number;
+    Dimension: Dimension;
+    FixType: number;
+    Raim: boolean;
+    Dte: boolean;
+    AssignedMode: boolean;
+}
+
+export interface StaticDataReport {
+    UserID: number;
+    PartNumber: boolean;
+    ReportA: StaticDataReportA;
+    ReportB: StaticDataReportB;
+}
+
+export interface StaticDataReportA {
+    Valid: boolean;
+    Name: string;
+}
+
+export interface StaticDataReportB {
+    Valid: boolean;
+    ShipType: number;
+    VendorIDName: string;
+    VenderIDModel: number;
+    VenderIDSerial: number;
+    CallSign: string;
+    Dimension: Dimension;
+    FixType: number;
+}
+
+export interface BaseStationReport {
+    UserID: number;
+    UtcYear: number;
+    UtcMonth: number;
+    UtcDay: number;
+    UtcHour: number;
+    UtcMinute: number;
+    UtcSecond: number;
+    PositionAccuracy: boolean;
+    Longitude: number | null;
+    Latitude: number | null;
+    FixType: number;
+    LongRangeEnable: boolean;
+    Raim: boolean;
+}
+
+export interface AidsToNavigationReport {
+    UserID: number;
+    Type: number;
+    Name: string;
+    PositionAccuracy: boolean;
+    Longitude: number | null;
+    Latitude: number | null;
+    Dimension: Dimension;
+    Fixtype: number;
+    Timestamp: number;
+    OffPosition: boolean;
+    AtoN: number;
+    Raim: boolean;
+    VirtualAtoN: boolean;
+    AssignedMode: boolean;
+    NameExtension: string;
+}
+
+export interface StandardSearchAndRescueAircraftReport {
+    UserID: number;
+    Altitude: number | null;
+    Sog: number | null;
+    PositionAccuracy: boolean;
+    Longitude: number | null;
+    Latitude: number | null;
+    Cog: number | null;
+    Timestamp: number;
+    AltFromBaro: boolean;
+    Dte: boolean;
+    AssignedMode: boolean;
+    Raim: boolean;
+}
+
+export interface LongRangeAisBroadcastMessage {
+    UserID: number;
+    PositionAccuracy: boolean;
+    Raim: boolean;
+    NavigationalStatus: number;
+    Longitude: number | null;
+    Latitude: number | null;
+    Sog: number | null;
+    Cog: number | null;
+    PositionLatency: boolean;
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/ioutil"
+	"github.com/urfave/cli/v3"
+)
+
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "none"
+	}
+}
+
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+func parseSeverity(s string) (Severity, error) {
+	switch s {
+	case "warning":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	case "none":
+		return SeverityNone, nil
+	default:
+		return SeverityNone, fmt.Errorf("invalid severity")
+	}
+}
+
+const (
+	issueMalformedRecord     = "malformed-record"
+	issueMalformedSentence   = "malformed-sentence"
+	issueChecksumFailure     = "checksum-failure"
+	issueIncompleteMultipart = "incomplete-multipart"
+	issueTimestampBackwards  = "timestamp-backwards"
+	issueDuplicateRecord     = "duplicate-record"
+	issueSilentPeriod        = "silent-period"
+)
+
+var issueSeverities = map[string]Severity{
+	issueMalformedRecord:     SeverityError,
+	issueMalformedSentence:   SeverityWarning,
+	issueChecksumFailure:     SeverityWarning,
+	issueIncompleteMultipart: SeverityWarning,
+	issueTimestampBackwards:  SeverityWarning,
+	issueDuplicateRecord:     SeverityWarning,
+	issueSilentPeriod:        SeverityWarning,
+}
+
+type ValidationIssue struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Severity Severity `json:"severity"`
+	Kind     string   `json:"kind"`
+	Message  string   `json:"message"`
+}
+
+type ValidationReport struct {
+	Records     int               `json:"records"`
+	Counts      map[string]int    `json:"counts"`
+	MaxSeverity Severity          `json:"maxSeverity"`
+	Issues      []ValidationIssue `json:"issues"`
+}
+
+type multipartGroup struct {
+	line      int
+	fragments int
+	received  uint32
+}
+
+// logValidator validates log files one at a time, adding the issues found to a common report.
+type logValidator struct {
+	report       ValidationReport
+	maxIssues    int
+	gapThreshold time.Duration
+
+	file            string
+	fileIssues      int
+	previous        *format.LoggerRecord
+	multipartGroups map[string]*multipartGroup
+	recentRecords   map[string]int64
+	latestTimestamp int64
+}
+
+func (validator *logValidator) addIssue(line int, kind string, message string) {
+	severity := issueSeverities[kind]
+	validator.report.Counts[kind]++
+	if severity > validator.report.MaxSeverity {
+		validator.report.MaxSeverity = severity
+	}
+	if (validator.maxIssues < 0) || (validator.report.Counts[kind] <= validator.maxIssues) {
+		validator.report.Issues = append(validator.report.Issues, ValidationIssue{
+			File:     validator.file,
+			Line:     line,
+			Severity: severity,
+			Kind:     kind,
+			Message:  message,
+		})
+	}
+}
+
+func (validator *logValidator) validate(line int, loggerRecord *format.LoggerRecord) {
+	validator.report.Records++
+
+	if validator.previous != nil {
+		dt := time.Duration(loggerRecord.Timestamp-validator.previous.Timestamp) * time.Millisecond
+		if dt < 0 {
+			validator.addIssue(line, issueTimestampBackwards, fmt.Sprintf("timestamp goes back by %s", -dt))
+		} else if (validator.gapThreshold > 0) && (dt > validator.gapThreshold) {
+			validator.addIssue(line, issueSilentPeriod, fmt.Sprintf("no records for %s since %s", dt,
+				time.UnixMilli(validator.previous.Timestamp).UTC().Format(time.RFC3339)))
+		}
+	}
+	validator.previous = loggerRecord
+
+	// Identical records have identical timestamps, so only recent records need to be remembered.
+	const duplicateHorizon = 60 * 1000
+	key := strconv.FormatInt(loggerRecord.Timestamp, 10) + "\x00" + loggerRecord.NMEA + "\x00" + loggerRecord.Source
+	if _, ok := validator.recentRecords[key]; ok {
+		validator.addIssue(line, issueDuplicateRecord, "duplicate record")
+	}
+	validator.recentRecords[key] = loggerRecord.Timestamp
+	if loggerRecord.Timestamp > validator.latestTimestamp+duplicateHorizon {
+		validator.latestTimestamp = loggerRecord.Timestamp
+		for key, timestamp := range validator.recentRecords {
+			if timestamp < validator.latestTimestamp-duplicateHorizon {
+				delete(validator.recentRecords, key)
+			}
+		}
+	}
+
+	sentenceInfo, err := format.ParseSentenceInfo(loggerRecord.NMEA)
+	if err != nil {
+		validator.addIssue(line, issueMalformedSentence, err.Error())
+		return
+	}
+	if !sentenceInfo.ChecksumValid {
+		validator.addIssue(line, issueChecksumFailure, "checksum mismatch")
+		return
+	}
+	if sentenceInfo.Encapsulated {
+		validator.validateMultipart(line, sentenceInfo)
+	}
+}
+
+func (validator *logValidator) validateMultipart(line int, sentenceInfo *format.SentenceInfo) {
+	if len(sentenceInfo.Fields) < 4 {
+		return
+	}
+	fragments, err1 := strconv.Atoi(sentenceInfo.Fields[0])
+	fragment, err2 := strconv.Atoi(sentenceInfo.Fields[1])
+	if (err1 != nil) || (err2 != nil) || (fragments <= 1) || (fragments > 9) || (fragment < 1) || (fragment > fragments) {
+		return
+	}
+
+	key := sentenceInfo.Prefix() + "," + sentenceInfo.Fields[0] + "," + sentenceInfo.Fields[2] + "," + sentenceInfo.Fields[3]
+	group := validator.multipartGroups[key]
+	if (group != nil) && ((fragment == 1) || (group.received&(1<<fragment) != 0)) {
+		validator.addIssue(group.line, issueIncompleteMultipart, fmt.Sprintf("received %d of %d parts",
+			countBits(group.received), group.fragments))
+		group = nil
+	}
+	if group == nil {
+		group = &multipartGroup{
+			line:      line,
+			fragments: fragments,
+		}
+		validator.multipartGroups[key] = group
+	}
+	group.received |= 1 << fragment
+	if countBits(group.received) == group.fragments {
+		delete(validator.multipartGroups, key)
+	}
+}
+
+func (validator *logValidator) finish() {
+	var groups []*multipartGroup
+	for _, group := range validator.multipartGroups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].line < groups[j].line
+	})
+	for _, group := range groups {
+		validator.addIssue(group.line, issueIncompleteMultipart, fmt.Sprintf("received %d of %d parts",
+			countBits(group.received), group.fragments))
+	}
+	fileIssues := validator.report.Issues[validator.fileIssues:]
+	sort.SliceStable(fileIssues, func(i, j int) bool {
+		return fileIssues[i].Line < fileIssues[j].Line
+	})
+}
+
+// validateFile validates a log file on its own: timestamps, duplicates and multipart messages are not checked across
+// files.
+func (validator *logValidator) validateFile(path string) error {
+	validator.file = path
+	validator.fileIssues = len(validator.report.Issues)
+	validator.previous = nil
+	validator.multipartGroups = make(map[string]*multipartGroup)
+	validator.recentRecords = make(map[string]int64)
+	validator.latestTimestamp = 0
+
+	reader, err := ioutil.OpenFileForReading(path)
+	if err != nil {
+		return err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	loggerRecordReader := format.NewLoggerRecordReader(reader)
+	for {
+		loggerRecord, err := loggerRecordReader.ReadLoggerRecord()
+		if err != nil {
+			var malformedRecordError *format.MalformedRecordError
+			if errors.As(err, &malformedRecordError) {
+				validator.addIssue(malformedRecordError.Line, issueMalformedRecord, malformedRecordError.Err.Error())
+				continue
+			}
+			return err
+		}
+		if loggerRecord == nil {
+			break
+		}
+		validator.validate(loggerRecordReader.Line(), loggerRecord)
+	}
+	validator.finish()
+	return nil
+}
+
+func countBits(v uint32) int {
+	n := 0
+	for ; v != 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
+
+func doValidate(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	gapThreshold := cmd.Duration(gapThresholdFlag.Name)
+	outputFormat := cmd.String(outputFormatFlag.Name)
+	maxIssues := cmd.Int(maxIssuesFlag.Name)
+	failOn, err := parseSeverity(cmd.String(failOnFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	validator := &logValidator{
+		report: ValidationReport{
+			Counts: make(map[string]int),
+			Issues: []ValidationIssue{},
+		},
+		maxIssues:    maxIssues,
+		gapThreshold: gapThreshold,
+	}
+	for _, inputFile := range inputFiles {
+		err = validator.validateFile(inputFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch outputFormat {
+	case "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		err = jsonEncoder.Encode(validator.report)
+	default:
+		err = printValidationReport(os.Stdout, &validator.report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if (failOn != SeverityNone) && (validator.report.MaxSeverity >= failOn) {
+		return cli.Exit(fmt.Sprintf("validation failed (%s)", validator.report.MaxSeverity), 1)
+	}
+	return nil
+}
+
+func printValidationReport(w io.Writer, report *ValidationReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, issue := range report.Issues {
+		_, _ = fmt.Fprintf(tw, "%s:%d\t%s\t%s\t%s\n", issue.File, issue.Line, issue.Severity, issue.Kind, issue.Message)
+	}
+	if len(report.Issues) > 0 {
+		_, _ = fmt.Fprintln(tw)
+	}
+	_, _ = fmt.Fprintf(tw, "Records:\t%d\n", report.Records)
+	var kinds []string
+	for kind := range report.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		_, _ = fmt.Fprintf(tw, "%s:\t%d\n", kind, report.Counts[kind])
+	}
+	_, _ = fmt.Fprintf(tw, "Result:\t%s\n", report.MaxSeverity)
+	return tw.Flush()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+)
+
+// vesselTrackPoint is a position reported by a vessel. sog (knots) and cog (degrees) are nil if not available.
+// navigationalStatus is nil if the message type does not carry it.
+type vesselTrackPoint struct {
+	timestamp          int64
+	point              geo.Point
+	sog                *float64
+	cog                *float64
+	navigationalStatus *uint8
+}
+
+// vesselTrackPointOf returns the position reported by a vessel (types 1-3, 18, 19 and 27). ok is false if the record is
+// not a vessel position report, or if the position is not available.
+func vesselTrackPointOf(record *format.AISRecord) (trackPoint vesselTrackPoint, ok bool) {
+	packet := record.AIS.Packet
+	latitude, longitude, ok := format.AISPosition(packet)
+	if !ok {
+		return trackPoint, false
+	}
+	trackPoint = vesselTrackPoint{
+		timestamp: record.Timestamp,
+		point: geo.Point{
+			Longitude: longitude,
+			Latitude:  latitude,
+		},
+	}
+	var sog, cog float64
+	switch report := packet.(type) {
+	case ais.PositionReport:
+		sog, cog = float64(report.Sog), float64(report.Cog)
+		trackPoint.navigationalStatus = &report.NavigationalStatus
+	case ais.StandardClassBPositionReport:
+		sog, cog = float64(report.Sog), float64(report.Cog)
+	case ais.ExtendedClassBPositionReport:
+		sog, cog = float64(report.Sog), float64(report.Cog)
+	case ais.LongRangeAisBroadcastMessage:
+		sog, cog = float64(report.Sog), float64(report.Cog)
+		trackPoint.navigationalStatus = &report.NavigationalStatus
+	default:
+		return trackPoint, false
+	}
+	notAvailableFields := format.AISNotAvailableFields(packet)
+	if !slices.Contains(notAvailableFields, "Sog") {
+		trackPoint.sog = &sog
+	}
+	if !slices.Contains(notAvailableFields, "Cog") {
+		trackPoint.cog = &cog
+	}
+	return trackPoint, true
+}
+
+func sortedMapKeys[T any](m map[uint32]T) []uint32 {
+	keys := make([]uint32, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/urfave/cli/v3"
+)
+
+// Vessel is an entry of the vessel registry. Static data fields are nil if they have not been reported.
+type Vessel struct {
+	MMSI        uint32           `json:"mmsi"`
+	Class       string           `json:"class,omitempty"`
+	Name        *string          `json:"name,omitempty"`
+	CallSign    *string          `json:"callSign,omitempty"`
+	IMO         *uint32          `json:"imo,omitempty"`
+	ShipType    *uint8           `json:"shipType,omitempty"`
+	Dimension   *VesselDimension `json:"dimension,omitempty"`
+	Draught     *float64         `json:"draught,omitempty"`
+	Destination *string          `json:"destination,omitempty"`
+	ETA         *string          `json:"eta,omitempty"`
+	FirstSeen   time.Time        `json:"firstSeen"`
+	LastSeen    time.Time        `json:"lastSeen"`
+	Messages    int              `json:"messages"`
+	History     []VesselChange   `json:"history"`
+}
+
+type VesselDimension struct {
+	A uint16 `json:"a"`
+	B uint16 `json:"b"`
+	C uint8  `json:"c"`
+	D uint8  `json:"d"`
+}
+
+// VesselChange records a static data field taking a new value.
+type VesselChange struct {
+	Time  time.Time `json:"time"`
+	Field string    `json:"field"`
+	From  any       `json:"from"`
+	To    any       `json:"to"`
+}
+
+// vesselField is the value of a static data field carried by a message.
+type vesselField struct {
+	name  string
+	value any
+}
+
+// vesselFields returns the vessel class and the static data fields carried by packet. ok is false if packet is not
+// sent by vessels.
+func vesselFields(packet ais.Packet) (class string, fields []vesselField, ok bool) {
+	switch report := packet.(type) {
+	case ais.PositionReport:
+		return "A", nil, true
+
+	case ais.ShipStaticData:
+		return "A", []vesselField{
+			{"name", strings.TrimSpace(report.Name)},
+			{"callSign", strings.TrimSpace(report.CallSign)},
+			{"imo", report.ImoNumber},
+			{"shipType", report.Type},
+			{"dimension", vesselDimension(report.Dimension)},
+			{"draught", float64(report.MaximumStaticDraught)},
+			{"destination", strings.TrimSpace(report.Destination)},
+			{"eta", fmt.Sprintf("%02d-%02d %02d:%02d", report.Eta.Month, report.Eta.Day, report.Eta.Hour, report.Eta.Minute)},
+		}, true
+
+	case ais.StandardClassBPositionReport:
+		return "B", nil, true
+
+	case ais.ExtendedClassBPositionReport:
+		return "B", []vesselField{
+			{"name", strings.TrimSpace(report.Name)},
+			{"shipType", report.Type},
+			{"dimension", vesselDimension(report.Dimension)},
+		}, true
+
+	case ais.StaticDataReport:
+		if report.ReportA.Valid {
+			fields = append(fields, vesselField{"name", strings.TrimSpace(report.ReportA.Name)})
+		}
+		if report.ReportB.Valid {
+			fields = append(fields,
+				vesselField{"callSign", strings.TrimSpace(report.ReportB.CallSign)},
+				vesselField{"shipType", report.ReportB.ShipType},
+				vesselField{"dimension", vesselDimension(report.ReportB.Dimension)},
+			)
+		}
+		return "B", fields, true
+
+	case ais.LongRangeAisBroadcastMessage:
+		return "", nil, true
+	}
+	return "", nil, false
+}
+
+func vesselDimension(dimension ais.FieldDimension) VesselDimension {
+	return VesselDimension{
+		A: dimension.A,
+		B: dimension.B,
+		C: dimension.C,
+		D: dimension.D,
+	}
+}
+
+type vesselRegistry struct {
+	vesselMap map[uint32]*Vessel
+	valueMap  map[uint32]map[string]any
+}
+
+func newVesselRegistry() *vesselRegistry {
+	return &vesselRegistry{
+		vesselMap: make(map[uint32]*Vessel),
+		valueMap:  make(map[uint32]map[string]any),
+	}
+}
+
+func (registry *vesselRegistry) add(record *format.AISRecord) {
+	class, fields, ok := vesselFields(record.AIS.Packet)
+	if !ok {
+		return
+	}
+	t := time.UnixMilli(record.Timestamp).UTC()
+	mmsi := record.AIS.Packet.GetHeader().UserID
+	vessel, ok := registry.vesselMap[mmsi]
+	if !ok {
+		vessel = &Vessel{
+			MMSI:      mmsi,
+			FirstSeen: t,
+			History:   []VesselChange{},
+		}
+		registry.vesselMap[mmsi] = vessel
+		registry.valueMap[mmsi] = make(map[string]any)
+	}
+	if t.Before(vessel.FirstSeen) {
+		vessel.FirstSeen = t
+	}
+	if t.After(vessel.LastSeen) {
+		vessel.LastSeen = t
+	}
+	vessel.Messages++
+	if class != "" {
+		vessel.Class = class
+	}
+
+	values := registry.valueMap[mmsi]
+	for _, field := range fields {
+		value, ok := values[field.name]
+		if ok && (value != field.value) {
+			vessel.History = append(vessel.History, VesselChange{
+				Time:  t,
+				Field: field.name,
+				From:  value,
+				To:    field.value,
+			})
+		}
+		values[field.name] = field.value
+		vessel.set(field)
+	}
+}
+
+func (vessel *Vessel) set(field vesselField) {
+	switch field.name {
+	case "name":
+		value := field.value.(string)
+		vessel.Name = &value
+	case "callSign":
+		value := field.value.(string)
+		vessel.CallSign = &value
+	case "imo":
+		value := field.value.(uint32)
+		vessel.IMO = &value
+	case "shipType":
+		value := field.value.(uint8)
+		vessel.ShipType = &value
+	case "dimension":
+		value := field.value.(VesselDimension)
+		vessel.Dimension = &value
+	case "draught":
+		value := field.value.(float64)
+		vessel.Draught = &value
+	case "destination":
+		value := field.value.(string)
+		vessel.Destination = &value
+	case "eta":
+		value := field.value.(string)
+		vessel.ETA = &value
+	}
+}
+
+// vessels returns the vessels, ordered by MMSI.
+func (registry *vesselRegistry) vessels() []*Vessel {
+	var vessels []*Vessel
+	for _, vessel := range registry.vesselMap {
+		vessels = append(vessels, vessel)
+	}
+	sort.Slice(vessels, func(i, j int) bool {
+		return vessels[i].MMSI < vessels[j].MMSI
+	})
+	return vessels
+}
+
+func doAisVessels(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+	outputFormat := cmd.String(jsonCsvFormatFlag.Name)
+	history := cmd.Bool(historyFlag.Name)
+
+	registry := newVesselRegistry()
+
+	err := readAISRecords(inputFiles, aisRecordOptions{
+		MMSIs:        mmsis,
+		DedupeWindow: dedupeWindow,
+	}, func(aisRecord *format.AISRecord) error {
+		registry.add(aisRecord)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	vessels := registry.vessels()
+	switch {
+	case outputFormat == "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		if vessels == nil {
+			vessels = []*Vessel{}
+		}
+		return jsonEncoder.Encode(vessels)
+
+	case history:
+		return writeVesselHistoryCsv(os.Stdout, vessels)
+
+	default:
+		return writeVesselsCsv(os.Stdout, vessels)
+	}
+}
+
+func writeVesselsCsv(w io.Writer, vessels []*Vessel) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"MMSI", "CLASS", "NAME", "CALLSIGN", "IMO", "SHIPTYPE", "A", "B", "C", "D", "DRAUGHT", "DESTINATION", "ETA",
+		"FIRST SEEN", "LAST SEEN", "MESSAGES", "CHANGES",
+	})
+	if err != nil {
+		return err
+	}
+	for _, vessel := range vessels {
+		dimension := []string{"", "", "", ""}
+		if vessel.Dimension != nil {
+			dimension = []string{
+				strconv.FormatInt(int64(vessel.Dimension.A), 10),
+				strconv.FormatInt(int64(vessel.Dimension.B), 10),
+				strconv.FormatInt(int64(vessel.Dimension.C), 10),
+				strconv.FormatInt(int64(vessel.Dimension.D), 10),
+			}
+		}
+		var cells []string
+		cells = append(cells,
+			strconv.FormatInt(int64(vessel.MMSI), 10),
+			vessel.Class,
+			formatOptional(vessel.Name),
+			formatOptional(vessel.CallSign),
+			formatOptional(vessel.IMO),
+			formatOptional(vessel.ShipType),
+		)
+		cells = append(cells, dimension...)
+		cells = append(cells,
+			formatOptional(vessel.Draught),
+			formatOptional(vessel.Destination),
+			formatOptional(vessel.ETA),
+			vessel.FirstSeen.Format(time.RFC3339Nano),
+			vessel.LastSeen.Format(time.RFC3339Nano),
+			strconv.Itoa(vessel.Messages),
+			strconv.Itoa(len(vessel.History)),
+		)
+		err = csvWriter.Write(cells)
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeVesselHistoryCsv(w io.Writer, vessels []*Vessel) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"MMSI", "TIME", "FIELD", "FROM", "TO"})
+	if err != nil {
+		return err
+	}
+	for _, vessel := range vessels {
+		for _, change := range vessel.History {
+			err = csvWriter.Write([]string{
+				strconv.FormatInt(int64(vessel.MMSI), 10),
+				change.Time.Format(time.RFC3339Nano),
+				change.Field,
+				formatVesselValue(change.From),
+				formatVesselValue(change.To),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func formatOptional[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return formatVesselValue(*value)
+}
+
+func formatVesselValue(value any) string {
+	switch v := value.(type) {
+	case VesselDimension:
+		return fmt.Sprintf("%d/%d/%d/%d", v.A, v.B, v.C, v.D)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
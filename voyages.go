@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngyewch/nmea-logger/format"
+	"github.com/ngyewch/nmea-logger/geo"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	phaseMoored   = "moored"
+	phaseAnchored = "anchored"
+	phaseUnderway = "underway"
+)
+
+// VesselVoyages is the voyage analysis of a vessel.
+type VesselVoyages struct {
+	MMSI      uint32        `json:"mmsi"`
+	Phases    []VoyagePhase `json:"phases"`
+	PortCalls []PortCall    `json:"portCalls"`
+	Voyages   []Voyage      `json:"voyages"`
+}
+
+// VoyagePhase is a period during which a vessel is moored, anchored or underway. Distance is in nautical miles.
+type VoyagePhase struct {
+	State     string    `json:"state"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Port      string    `json:"port,omitempty"`
+	Distance  float64   `json:"distance"`
+	Positions int       `json:"positions"`
+
+	from int
+	to   int
+}
+
+// PortCall is a stay in a port, from the start of the first moored or anchored phase in the port to the end of the
+// last one.
+type PortCall struct {
+	Port      string    `json:"port"`
+	Arrival   time.Time `json:"arrival"`
+	Departure time.Time `json:"departure"`
+
+	firstPhase int
+	lastPhase  int
+}
+
+// Voyage is a passage between two port calls. From, To, Departure and Arrival are nil if the vessel was not seen in
+// port at that end of the voyage. Distance is in nautical miles.
+type Voyage struct {
+	From      *string    `json:"from"`
+	To        *string    `json:"to"`
+	Departure *time.Time `json:"departure"`
+	Arrival   *time.Time `json:"arrival"`
+	Distance  float64    `json:"distance"`
+}
+
+type voyagePort struct {
+	name     string
+	contains func(p geo.Point) bool
+}
+
+// loadVoyagePorts loads ports from a GeoJSON file of polygons, or from a CSV file of points (see geo.LoadCircles).
+func loadVoyagePorts(path string, radius float64) ([]voyagePort, error) {
+	var ports []voyagePort
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		circles, err := geo.LoadCircles(path, radius)
+		if err != nil {
+			return nil, err
+		}
+		for _, circle := range circles {
+			ports = append(ports, voyagePort{
+				name:     circle.Name,
+				contains: circle.Contains,
+			})
+		}
+		return ports, nil
+	}
+	polygons, err := geo.LoadPolygons(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, polygon := range polygons {
+		ports = append(ports, voyagePort{
+			name:     polygon.Name,
+			contains: polygon.Contains,
+		})
+	}
+	return ports, nil
+}
+
+type voyageTrackPoint struct {
+	vesselTrackPoint
+	port string
+}
+
+type voyageAnalyzer struct {
+	ports           []voyagePort
+	stationarySpeed float64
+	minDwell        time.Duration
+	trackMap        map[uint32][]voyageTrackPoint
+}
+
+func (analyzer *voyageAnalyzer) add(record *format.AISRecord) {
+	trackPoint, ok := vesselTrackPointOf(record)
+	if !ok {
+		return
+	}
+	mmsi := record.AIS.Packet.GetHeader().UserID
+	analyzer.trackMap[mmsi] = append(analyzer.trackMap[mmsi], voyageTrackPoint{
+		vesselTrackPoint: trackPoint,
+	})
+}
+
+func (analyzer *voyageAnalyzer) portOf(p geo.Point) string {
+	for _, port := range analyzer.ports {
+		if port.contains(p) {
+			return port.name
+		}
+	}
+	return ""
+}
+
+// state classifies a track point. A vessel is underway if its speed is at least stationarySpeed. A stationary vessel is
+// moored or anchored according to its navigational status, or else moored if it is in port and anchored if not.
+// state returns "" if the speed is not available and the navigational status does not tell.
+func (analyzer *voyageAnalyzer) state(trackPoint voyageTrackPoint) string {
+	if (trackPoint.sog != nil) && (*trackPoint.sog >= analyzer.stationarySpeed) {
+		return phaseUnderway
+	}
+	if trackPoint.navigationalStatus != nil {
+		switch *trackPoint.navigationalStatus {
+		case 1:
+			return phaseAnchored
+		case 5:
+			return phaseMoored
+		}
+	}
+	if trackPoint.sog == nil {
+		return ""
+	}
+	if trackPoint.port != "" {
+		return phaseMoored
+	}
+	return phaseAnchored
+}
+
+func (analyzer *voyageAnalyzer) vesselVoyages() []*VesselVoyages {
+	var vesselVoyagesList []*VesselVoyages
+	for _, mmsi := range sortedMapKeys(analyzer.trackMap) {
+		vesselVoyagesList = append(vesselVoyagesList, analyzer.analyze(mmsi, analyzer.trackMap[mmsi]))
+	}
+	return vesselVoyagesList
+}
+
+func (analyzer *voyageAnalyzer) analyze(mmsi uint32, track []voyageTrackPoint) *VesselVoyages {
+	sort.SliceStable(track, func(i, j int) bool {
+		return track[i].timestamp < track[j].timestamp
+	})
+	// distances[i] is the distance travelled from the first track point to track point i, in metres.
+	distances := make([]float64, len(track))
+	for i := range track {
+		track[i].port = analyzer.portOf(track[i].point)
+		if i > 0 {
+			distances[i] = distances[i-1] + geo.Distance(track[i-1].point, track[i].point)
+		}
+	}
+
+	phases := analyzer.segment(track)
+	for i := range phases {
+		phase := &phases[i]
+		phase.Start = time.UnixMilli(track[phase.from].timestamp).UTC()
+		phase.End = time.UnixMilli(track[phase.to].timestamp).UTC()
+		phase.Distance = (distances[phase.to] - distances[phase.from]) / geo.MetresPerNauticalMile
+		phase.Positions = phase.to - phase.from
+		if i == len(phases)-1 {
+			phase.Positions++
+		}
+		if phase.State == phaseUnderway {
+			phase.Port = phasePort(track[phase.from : phase.to+1])
+		} else {
+			phase.Port = track[phase.from].port
+		}
+	}
+
+	portCalls := portCallsOf(phases)
+	vesselVoyages := &VesselVoyages{
+		MMSI:      mmsi,
+		Phases:    phases,
+		PortCalls: portCalls,
+		Voyages:   []Voyage{},
+	}
+	if vesselVoyages.Phases == nil {
+		vesselVoyages.Phases = []VoyagePhase{}
+	}
+	if vesselVoyages.PortCalls == nil {
+		vesselVoyages.PortCalls = []PortCall{}
+	}
+
+	// Voyages run between consecutive port calls. The passages before the first and after the last port call are
+	// voyages with an unknown end, if the vessel was underway during them.
+	firstPhase := 0
+	var from *PortCall
+	for i := 0; i <= len(portCalls); i++ {
+		lastPhase := len(phases) - 1
+		var to *PortCall
+		if i < len(portCalls) {
+			to = &portCalls[i]
+			lastPhase = to.firstPhase - 1
+		}
+		underway := false
+		for _, phase := range phases[firstPhase : lastPhase+1] {
+			underway = underway || (phase.State == phaseUnderway)
+		}
+		if underway {
+			voyage := Voyage{}
+			fromIndex := phases[firstPhase].from
+			toIndex := phases[lastPhase].to
+			if from != nil {
+				voyage.From = &from.Port
+				voyage.Departure = &from.Departure
+			}
+			if to != nil {
+				voyage.To = &to.Port
+				voyage.Arrival = &to.Arrival
+			}
+			voyage.Distance = (distances[toIndex] - distances[fromIndex]) / geo.MetresPerNauticalMile
+			vesselVoyages.Voyages = append(vesselVoyages.Voyages, voyage)
+		}
+		if to != nil {
+			firstPhase = to.lastPhase + 1
+			from = to
+		}
+	}
+	return vesselVoyages
+}
+
+// segment splits a track into phases of the same state. A phase runs from its first track point to the first track
+// point of the next phase. Moored and anchored phases shorter than minDwell are treated as underway.
+func (analyzer *voyageAnalyzer) segment(track []voyageTrackPoint) []VoyagePhase {
+	var phases []VoyagePhase
+	state := ""
+	for i, trackPoint := range track {
+		pointState := analyzer.state(trackPoint)
+		if pointState == "" {
+			pointState = state
+		}
+		if pointState == "" {
+			pointState = phaseUnderway
+		}
+		if (len(phases) > 0) && (pointState == state) {
+			continue
+		}
+		if len(phases) > 0 {
+			phases[len(phases)-1].to = i
+		}
+		phases = append(phases, VoyagePhase{
+			State: pointState,
+			from:  i,
+			to:    i,
+		})
+		state = pointState
+	}
+	if len(phases) > 0 {
+		phases[len(phases)-1].to = len(track) - 1
+	}
+
+	minDwell := analyzer.minDwell.Milliseconds()
+	var merged []VoyagePhase
+	for _, phase := range phases {
+		if (phase.State != phaseUnderway) && (track[phase.to].timestamp-track[phase.from].timestamp < minDwell) {
+			phase.State = phaseUnderway
+		}
+		if (len(merged) > 0) && (merged[len(merged)-1].State == phase.State) {
+			merged[len(merged)-1].to = phase.to
+			continue
+		}
+		merged = append(merged, phase)
+	}
+	return merged
+}
+
+// phasePort returns the port that contains all the track points, or "".
+func phasePort(track []voyageTrackPoint) string {
+	port := track[0].port
+	for _, trackPoint := range track[1:] {
+		if trackPoint.port != port {
+			return ""
+		}
+	}
+	return port
+}
+
+// portCallsOf returns the port calls of a vessel. Moored and anchored phases in the same port belong to the same port
+// call, if the phases between them do not leave the port.
+func portCallsOf(phases []VoyagePhase) []PortCall {
+	var portCalls []PortCall
+	for i, phase := range phases {
+		if (phase.State == phaseUnderway) || (phase.Port == "") {
+			continue
+		}
+		if len(portCalls) > 0 {
+			portCall := &portCalls[len(portCalls)-1]
+			inPort := true
+			for _, between := range phases[portCall.lastPhase+1 : i] {
+				inPort = inPort && (between.Port == phase.Port)
+			}
+			if (portCall.Port == phase.Port) && inPort {
+				portCall.Departure = phase.End
+				portCall.lastPhase = i
+				continue
+			}
+		}
+		portCalls = append(portCalls, PortCall{
+			Port:       phase.Port,
+			Arrival:    phase.Start,
+			Departure:  phase.End,
+			firstPhase: i,
+			lastPhase:  i,
+		})
+	}
+	return portCalls
+}
+
+func doAisVoyages(ctx context.Context, cmd *cli.Command) error {
+	inputFiles := cmd.StringArgs(inputFilesArg.Name)
+	mmsis := cmd.Uint32Slice(mmsiFlag.Name)
+	dedupeWindow := cmd.Duration(dedupeWindowFlag.Name)
+	outputFormat := cmd.String(jsonCsvFormatFlag.Name)
+	phases := cmd.Bool(phasesFlag.Name)
+
+	analyzer := &voyageAnalyzer{
+		stationarySpeed: cmd.Float64(stationarySpeedFlag.Name),
+		minDwell:        cmd.Duration(minDwellFlag.Name),
+		trackMap:        make(map[uint32][]voyageTrackPoint),
+	}
+	portsFile := cmd.String(portsFlag.Name)
+	if portsFile != "" {
+		ports, err := loadVoyagePorts(portsFile, cmd.Float64(portRadiusFlag.Name))
+		if err != nil {
+			return err
+		}
+		analyzer.ports = ports
+	}
+
+	err := readAISRecords(inputFiles, aisRecordOptions{MMSIs: mmsis, DedupeWindow: dedupeWindow}, func(aisRecord *format.AISRecord) error {
+		analyzer.add(aisRecord)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	vesselVoyagesList := analyzer.vesselVoyages()
+	switch {
+	case outputFormat == "json":
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "  ")
+		if vesselVoyagesList == nil {
+			vesselVoyagesList = []*VesselVoyages{}
+		}
+		return jsonEncoder.Encode(vesselVoyagesList)
+
+	case phases:
+		return writeVoyagePhasesCsv(os.Stdout, vesselVoyagesList)
+
+	default:
+		return writeVoyagesCsv(os.Stdout, vesselVoyagesList)
+	}
+}
+
+func writeVoyagesCsv(w io.Writer, vesselVoyagesList []*VesselVoyages) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"MMSI", "FROM", "TO", "DEPARTURE", "ARRIVAL", "DISTANCE (NM)"})
+	if err != nil {
+		return err
+	}
+	for _, vesselVoyages := range vesselVoyagesList {
+		for _, voyage := range vesselVoyages.Voyages {
+			err = csvWriter.Write([]string{
+				strconv.FormatInt(int64(vesselVoyages.MMSI), 10),
+				formatOptional(voyage.From),
+				formatOptional(voyage.To),
+				formatOptional(voyage.Departure),
+				formatOptional(voyage.Arrival),
+				formatDistance(voyage.Distance),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeVoyagePhasesCsv(w io.Writer, vesselVoyagesList []*VesselVoyages) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{"MMSI", "STATE", "PORT", "START", "END", "DISTANCE (NM)", "POSITIONS"})
+	if err != nil {
+		return err
+	}
+	for _, vesselVoyages := range vesselVoyagesList {
+		for _, phase := range vesselVoyages.Phases {
+			err = csvWriter.Write([]string{
+				strconv.FormatInt(int64(vesselVoyages.MMSI), 10),
+				phase.State,
+				phase.Port,
+				phase.Start.Format(time.RFC3339Nano),
+				phase.End.Format(time.RFC3339Nano),
+				formatDistance(phase.Distance),
+				strconv.Itoa(phase.Positions),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func formatDistance(distance float64) string {
+	return fmt.Sprintf("%.2f", distance)
+}
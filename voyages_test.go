@@ -0,0 +1,229 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVoyageAnalyzerSegment(t *testing.T) {
+	analyzer := &voyageAnalyzer{
+		stationarySpeed: 0.5,
+		minDwell:        10 * time.Minute,
+	}
+	type wantPhase struct {
+		state    string
+		from, to int
+	}
+	tests := []struct {
+		name  string
+		track []voyageTrackPoint
+		want  []wantPhase
+	}{
+		{
+			"empty track",
+			nil,
+			nil,
+		},
+		{
+			"underway",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(10.0), nil, ""),
+				voyageTestPoint(10, ptr(12.0), nil, ""),
+			},
+			[]wantPhase{{phaseUnderway, 0, 1}},
+		},
+		{
+			"moored in port",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(10.0), nil, ""),
+				voyageTestPoint(1, ptr(0.0), nil, "A"),
+				voyageTestPoint(30, ptr(0.0), nil, "A"),
+				voyageTestPoint(31, ptr(10.0), nil, ""),
+			},
+			[]wantPhase{{phaseUnderway, 0, 1}, {phaseMoored, 1, 3}, {phaseUnderway, 3, 3}},
+		},
+		{
+			"anchored outside port",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(0.1), nil, ""),
+				voyageTestPoint(60, ptr(0.1), nil, ""),
+			},
+			[]wantPhase{{phaseAnchored, 0, 1}},
+		},
+		{
+			"navigational status",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(0.0), ptr(uint8(1)), "A"),
+				voyageTestPoint(20, nil, ptr(uint8(1)), "A"),
+				voyageTestPoint(40, nil, ptr(uint8(5)), "A"),
+				voyageTestPoint(60, nil, ptr(uint8(5)), "A"),
+			},
+			[]wantPhase{{phaseAnchored, 0, 2}, {phaseMoored, 2, 3}},
+		},
+		{
+			"short stop is underway",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(10.0), nil, ""),
+				voyageTestPoint(1, ptr(0.0), nil, ""),
+				voyageTestPoint(3, ptr(10.0), nil, ""),
+				voyageTestPoint(4, ptr(10.0), nil, ""),
+			},
+			[]wantPhase{{phaseUnderway, 0, 3}},
+		},
+		{
+			"speed not available keeps the state",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, ptr(0.0), nil, "A"),
+				voyageTestPoint(20, nil, nil, "A"),
+				voyageTestPoint(40, ptr(0.0), nil, "A"),
+			},
+			[]wantPhase{{phaseMoored, 0, 2}},
+		},
+		{
+			"speed not available at the start",
+			[]voyageTrackPoint{
+				voyageTestPoint(0, nil, nil, ""),
+				voyageTestPoint(1, ptr(10.0), nil, ""),
+			},
+			[]wantPhase{{phaseUnderway, 0, 1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			phases := analyzer.segment(test.track)
+			var got []wantPhase
+			for _, phase := range phases {
+				got = append(got, wantPhase{phase.State, phase.from, phase.to})
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("segment() = %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("segment() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPortCallsOf(t *testing.T) {
+	type wantPortCall struct {
+		port                  string
+		arrival, departure    int
+		firstPhase, lastPhase int
+	}
+	tests := []struct {
+		name   string
+		phases []VoyagePhase
+		want   []wantPortCall
+	}{
+		{
+			"no phases",
+			nil,
+			nil,
+		},
+		{
+			"underway only",
+			[]VoyagePhase{
+				voyageTestPhase(phaseUnderway, "A", 0, 10),
+			},
+			nil,
+		},
+		{
+			"stop outside port",
+			[]VoyagePhase{
+				voyageTestPhase(phaseUnderway, "", 0, 10),
+				voyageTestPhase(phaseAnchored, "", 10, 60),
+			},
+			nil,
+		},
+		{
+			"one port call",
+			[]VoyagePhase{
+				voyageTestPhase(phaseUnderway, "", 0, 10),
+				voyageTestPhase(phaseMoored, "A", 10, 60),
+				voyageTestPhase(phaseUnderway, "", 60, 70),
+			},
+			[]wantPortCall{{"A", 10, 60, 1, 1}},
+		},
+		{
+			"shift within port",
+			[]VoyagePhase{
+				voyageTestPhase(phaseAnchored, "A", 0, 30),
+				voyageTestPhase(phaseUnderway, "A", 30, 40),
+				voyageTestPhase(phaseMoored, "A", 40, 90),
+			},
+			[]wantPortCall{{"A", 0, 90, 0, 2}},
+		},
+		{
+			"leave and return",
+			[]VoyagePhase{
+				voyageTestPhase(phaseMoored, "A", 0, 30),
+				voyageTestPhase(phaseUnderway, "", 30, 40),
+				voyageTestPhase(phaseMoored, "A", 40, 90),
+			},
+			[]wantPortCall{{"A", 0, 30, 0, 0}, {"A", 40, 90, 2, 2}},
+		},
+		{
+			"two ports",
+			[]VoyagePhase{
+				voyageTestPhase(phaseMoored, "A", 0, 30),
+				voyageTestPhase(phaseUnderway, "", 30, 40),
+				voyageTestPhase(phaseMoored, "B", 40, 90),
+			},
+			[]wantPortCall{{"A", 0, 30, 0, 0}, {"B", 40, 90, 2, 2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			portCalls := portCallsOf(test.phases)
+			var got []wantPortCall
+			for _, portCall := range portCalls {
+				got = append(got, wantPortCall{
+					port:       portCall.Port,
+					arrival:    int(portCall.Arrival.Sub(time.UnixMilli(0)).Minutes()),
+					departure:  int(portCall.Departure.Sub(time.UnixMilli(0)).Minutes()),
+					firstPhase: portCall.firstPhase,
+					lastPhase:  portCall.lastPhase,
+				})
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("portCallsOf() = %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("portCallsOf() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// voyageTestPoint returns a track point at minute minutes.
+func voyageTestPoint(minute int64, sog *float64, navigationalStatus *uint8, port string) voyageTrackPoint {
+	return voyageTrackPoint{
+		vesselTrackPoint: vesselTrackPoint{
+			timestamp:          minute * time.Minute.Milliseconds(),
+			sog:                sog,
+			navigationalStatus: navigationalStatus,
+		},
+		port: port,
+	}
+}
+
+// voyageTestPhase returns a phase from minute start to minute end.
+func voyageTestPhase(state string, port string, start int64, end int64) VoyagePhase {
+	return VoyagePhase{
+		State: state,
+		Port:  port,
+		Start: time.UnixMilli(start * time.Minute.Milliseconds()),
+		End:   time.UnixMilli(end * time.Minute.Milliseconds()),
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}